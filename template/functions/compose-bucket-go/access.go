@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	authorizationv1beta1 "dev.upbound.io/models/io/upbound/azure/authorization/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Names of the composed resources used to grant data-plane access to the
+// bucket's blobs.
+const (
+	accessIdentityResourceName     resource.Name = "access-identity"
+	accessIdentityRoleResourceName resource.Name = "access-identity-role"
+
+	// accessGrantResourceNamePrefix prefixes the names of the composed role
+	// assignments for the XR's grants. The rest of the name is a hash of the
+	// grant, so that each grant keeps its composed resource when others are
+	// added to or removed from the list.
+	accessGrantResourceNamePrefix = "access-"
+	accessGrantHashLength         = 8
+)
+
+// accessRoleDefinitionIDs are the IDs of the built-in roles granted for each
+// access role.
+var accessRoleDefinitionIDs = map[string]string{
+	"Reader": "2a2b9908-6ea1-4ae2-8e65-a410df84e7d1", // Storage Blob Data Reader
+	"Writer": "ba92f5b4-2d11-453d-a403-e96b0029c9fe", // Storage Blob Data Contributor
+	"Owner":  "b7e6dc6d-f1e8-4753-8033-0f276bb0955b", // Storage Blob Data Owner
+}
+
+// defaultAccessIdentityRole is granted to the bucket's identity unless the XR
+// requests another role.
+const defaultAccessIdentityRole = "Writer"
+
+var (
+	// principalIDPattern matches the object ID of an Entra ID principal.
+	principalIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	// subscriptionIDPattern captures the subscription ID of an Azure resource
+	// ID.
+	subscriptionIDPattern = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/`)
+)
+
+// An accessGrant grants a principal data-plane access to the bucket's blobs.
+type accessGrant struct {
+	// ResourceName of the composed RoleAssignment.
+	ResourceName resource.Name
+
+	// PrincipalID is the object ID of the principal granted access.
+	PrincipalID string
+
+	// Role granted to the principal: Reader, Writer or Owner.
+	Role string
+
+	// ContainerResourceName of the composed container the grant is scoped
+	// to. Empty if the grant is scoped to the storage account.
+	ContainerResourceName resource.Name
+}
+
+// A bucketAccess is the data-plane access granted to the bucket's blobs.
+type bucketAccess struct {
+	// Grants to principals outside of the bucket.
+	Grants []accessGrant
+
+	// IdentityRole granted to the bucket's own identity. Empty if the bucket
+	// has no identity.
+	IdentityRole string
+}
+
+// accessFrom returns the data-plane access requested by the supplied
+// parameters, or nil if no access is requested. Grants may only be scoped to
+// the supplied containers. Workload identity federates the bucket's identity,
+// so it implies one.
+func accessFrom(params *v1alpha1.XStorageBucketSpecParameters, containers []bucketContainer) (*bucketAccess, error) {
+	a := ptr.Deref(params.Access, v1alpha1.XStorageBucketSpecParametersAccess{})
+	if params.Access == nil && params.WorkloadIdentity == nil {
+		return nil, nil
+	}
+
+	access := &bucketAccess{}
+	for i, g := range ptr.Deref(a.Grants, nil) {
+		grant := accessGrant{
+			PrincipalID: ptr.Deref(g.PrincipalID, ""),
+			Role:        string(ptr.Deref(g.Role, "")),
+		}
+		if !principalIDPattern.MatchString(grant.PrincipalID) {
+			return nil, errors.Errorf("invalid grant %d: principal ID %q must be the object ID of an Entra ID principal", i, grant.PrincipalID)
+		}
+		if _, ok := accessRoleDefinitionIDs[grant.Role]; !ok {
+			return nil, errors.Errorf("invalid grant %d: unsupported role %q; use one of Reader, Writer, Owner", i, grant.Role)
+		}
+
+		if name := ptr.Deref(g.Container, ""); name != "" {
+			j := slices.IndexFunc(containers, func(c bucketContainer) bool { return c.Name == name })
+			if j < 0 {
+				return nil, errors.Errorf("invalid grant %d: container %q isn't one of the bucket's containers", i, name)
+			}
+			grant.ContainerResourceName = containers[j].ResourceName
+		}
+
+		h := sha256.Sum256([]byte(grant.PrincipalID + "/" + grant.Role + "/" + string(grant.ContainerResourceName)))
+		grant.ResourceName = resource.Name(accessGrantResourceNamePrefix + hex.EncodeToString(h[:])[:accessGrantHashLength])
+		if slices.ContainsFunc(access.Grants, func(o accessGrant) bool { return o.ResourceName == grant.ResourceName }) {
+			return nil, errors.Errorf("invalid grant %d: duplicates an earlier grant", i)
+		}
+
+		access.Grants = append(access.Grants, grant)
+	}
+
+	if a.Identity != nil || params.WorkloadIdentity != nil {
+		access.IdentityRole = defaultAccessIdentityRole
+		if a.Identity != nil && a.Identity.Role != nil {
+			access.IdentityRole = string(*a.Identity.Role)
+		}
+		if _, ok := accessRoleDefinitionIDs[access.IdentityRole]; !ok {
+			return nil, errors.Errorf("invalid identity: unsupported role %q; use one of Reader, Writer, Owner", access.IdentityRole)
+		}
+	}
+
+	return access, nil
+}
+
+// ResourceNames returns the names of the composed role assignments that
+// grant access.
+func (a *bucketAccess) ResourceNames() []resource.Name {
+	if a == nil {
+		return nil
+	}
+	names := make([]resource.Name, 0, len(a.Grants)+1)
+	for _, g := range a.Grants {
+		names = append(names, g.ResourceName)
+	}
+	if a.IdentityRole != "" {
+		names = append(names, accessIdentityRoleResourceName)
+	}
+	return names
+}
+
+// RoleAssignments returns the composed role assignments that grant access.
+// Role assignments are scoped to the storage account or a container by ID, so
+// each is only returned once its scope has been observed.
+func (a *bucketAccess) RoleAssignments(observed map[resource.Name]resource.ObservedComposed) (map[resource.Name]*authorizationv1beta1.RoleAssignment, error) {
+	ras := make(map[resource.Name]*authorizationv1beta1.RoleAssignment)
+
+	accountID, err := observedAccountID(observed)
+	if err != nil || accountID == "" {
+		return ras, err
+	}
+
+	for _, g := range a.Grants {
+		scope := accountID
+		if g.ContainerResourceName != "" {
+			scope, err = observedContainerID(observed, g.ContainerResourceName)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot get container ID")
+			}
+		}
+		if scope != "" {
+			ras[g.ResourceName] = roleAssignment(g.PrincipalID, "", g.Role, scope)
+		}
+	}
+
+	if a.IdentityRole != "" {
+		ap, err := observedIdentityStatus(observed, accessIdentityResourceName)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get access identity")
+		}
+		if ap != nil && ptr.Deref(ap.PrincipalID, "") != "" {
+			ras[accessIdentityRoleResourceName] = roleAssignment(*ap.PrincipalID, "ServicePrincipal", a.IdentityRole, accountID)
+		}
+	}
+
+	return ras, nil
+}
+
+// roleAssignment returns a composed role assignment granting the supplied
+// principal a data-plane access role at the supplied scope. The principal
+// type may be empty if it's unknown.
+func roleAssignment(principalID, principalType, role, scope string) *authorizationv1beta1.RoleAssignment {
+	ra := &authorizationv1beta1.RoleAssignment{
+		APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+		Spec: &authorizationv1beta1.RoleAssignmentSpec{
+			ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+				PrincipalID:      ptr.To(principalID),
+				RoleDefinitionID: ptr.To(roleDefinitionID(scope, accessRoleDefinitionIDs[role])),
+				Scope:            ptr.To(scope),
+			},
+		},
+	}
+	if principalType != "" {
+		ra.Spec.ForProvider.PrincipalType = ptr.To(principalType)
+	}
+	return ra
+}
+
+// roleDefinitionID returns the subscription-scoped resource ID of the
+// supplied built-in role, in the subscription of the supplied scope.
+func roleDefinitionID(scope, role string) string {
+	m := subscriptionIDPattern.FindStringSubmatch(scope)
+	if m == nil {
+		return "/providers/Microsoft.Authorization/roleDefinitions/" + role
+	}
+	return "/subscriptions/" + m[1] + "/providers/Microsoft.Authorization/roleDefinitions/" + role
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	authorizationv1beta1 "dev.upbound.io/models/io/upbound/azure/authorization/v1beta1"
+	managedidentityv1beta1 "dev.upbound.io/models/io/upbound/azure/managedidentity/v1beta1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+const (
+	readerPrincipalID = "00000000-0000-0000-0000-000000000001"
+	writerPrincipalID = "00000000-0000-0000-0000-000000000002"
+)
+
+func TestAccessFrom(t *testing.T) {
+	containers := []bucketContainer{
+		{ResourceName: "container-uploads", Name: "uploads"},
+	}
+	reader := ptr.To(v1alpha1.XStorageBucketSpecParametersAccessGrantsItemRoleReader)
+	writer := ptr.To(v1alpha1.XStorageBucketSpecParametersAccessGrantsItemRoleWriter)
+
+	type want struct {
+		access *bucketAccess
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no access is requested, no access should be returned.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			want:   want{},
+		},
+		"Grants": {
+			reason: "Grants should be scoped to the storage account unless they name a container, and be named after a hash of the grant.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Access: &v1alpha1.XStorageBucketSpecParametersAccess{
+					Grants: &[]v1alpha1.XStorageBucketSpecParametersAccessGrantsItem{
+						{PrincipalID: ptr.To(readerPrincipalID), Role: reader},
+						{PrincipalID: ptr.To(writerPrincipalID), Role: writer, Container: ptr.To("uploads")},
+					},
+				},
+			},
+			want: want{
+				access: &bucketAccess{
+					Grants: []accessGrant{
+						{ResourceName: "access-a677846b", PrincipalID: readerPrincipalID, Role: "Reader"},
+						{ResourceName: "access-7ad2133f", PrincipalID: writerPrincipalID, Role: "Writer", ContainerResourceName: "container-uploads"},
+					},
+				},
+			},
+		},
+		"Identity": {
+			reason: "The bucket's identity should be granted the Writer role unless the XR requests another role.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Access: &v1alpha1.XStorageBucketSpecParametersAccess{
+					Identity: &v1alpha1.XStorageBucketSpecParametersAccessIdentity{},
+				},
+			},
+			want: want{
+				access: &bucketAccess{IdentityRole: "Writer"},
+			},
+		},
+		"WorkloadIdentity": {
+			reason: "Workload identity should imply the bucket's identity, which should be granted the Writer role.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				WorkloadIdentity: &v1alpha1.XStorageBucketSpecParametersWorkloadIdentity{},
+			},
+			want: want{
+				access: &bucketAccess{IdentityRole: "Writer"},
+			},
+		},
+		"InvalidPrincipalID": {
+			reason: "Grants to principals that aren't identified by an object ID should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Access: &v1alpha1.XStorageBucketSpecParametersAccess{
+					Grants: &[]v1alpha1.XStorageBucketSpecParametersAccessGrantsItem{
+						{PrincipalID: ptr.To("team-a@example.com"), Role: reader},
+					},
+				},
+			},
+			want: want{
+				err: errors.New(`invalid grant 0: principal ID "team-a@example.com" must be the object ID of an Entra ID principal`),
+			},
+		},
+		"UnknownContainer": {
+			reason: "Grants scoped to containers that aren't part of the bucket should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Access: &v1alpha1.XStorageBucketSpecParametersAccess{
+					Grants: &[]v1alpha1.XStorageBucketSpecParametersAccessGrantsItem{
+						{PrincipalID: ptr.To(readerPrincipalID), Role: reader, Container: ptr.To("downloads")},
+					},
+				},
+			},
+			want: want{
+				err: errors.New(`invalid grant 0: container "downloads" isn't one of the bucket's containers`),
+			},
+		},
+		"DuplicateGrant": {
+			reason: "Grants that duplicate an earlier grant should be rejected, because Azure rejects duplicate role assignments.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Access: &v1alpha1.XStorageBucketSpecParametersAccess{
+					Grants: &[]v1alpha1.XStorageBucketSpecParametersAccessGrantsItem{
+						{PrincipalID: ptr.To(readerPrincipalID), Role: reader},
+						{PrincipalID: ptr.To(readerPrincipalID), Role: reader},
+					},
+				},
+			},
+			want: want{
+				err: errors.New("invalid grant 1: duplicates an earlier grant"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := accessFrom(tc.params, containers)
+
+			if diff := cmp.Diff(tc.want.access, got); diff != "" {
+				t.Errorf("%s\naccessFrom(...): -want access, +got access:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\naccessFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRoleAssignments(t *testing.T) {
+	accountID := "/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.Storage/storageAccounts/examplexre848635a"
+	containerID := accountID + "/blobServices/default/containers/uploads"
+
+	observe := func(in any) resource.ObservedComposed {
+		c := composed.New()
+		_ = convertViaJSON(c, in)
+		return resource.ObservedComposed{Resource: c}
+	}
+	account := observe(&storagev1beta1.Account{
+		APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+		Status: &storagev1beta1.AccountStatus{
+			AtProvider: &storagev1beta1.AccountStatusAtProvider{ID: ptr.To(accountID)},
+		},
+	})
+	container := observe(&storagev1beta1.Container{
+		APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+		Status: &storagev1beta1.ContainerStatus{
+			AtProvider: &storagev1beta1.ContainerStatusAtProvider{ResourceManagerID: ptr.To(containerID)},
+		},
+	})
+	identity := observe(&managedidentityv1beta1.UserAssignedIdentity{
+		APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+		Status: &managedidentityv1beta1.UserAssignedIdentityStatus{
+			AtProvider: &managedidentityv1beta1.UserAssignedIdentityStatusAtProvider{PrincipalID: ptr.To("principal")},
+		},
+	})
+
+	access := &bucketAccess{
+		Grants: []accessGrant{
+			{ResourceName: "access-a677846b", PrincipalID: readerPrincipalID, Role: "Reader"},
+			{ResourceName: "access-7ad2133f", PrincipalID: writerPrincipalID, Role: "Writer", ContainerResourceName: "container-uploads"},
+		},
+		IdentityRole: "Owner",
+	}
+
+	roleAssignment := func(principalID, principalType, role, scope string) *authorizationv1beta1.RoleAssignment {
+		ra := &authorizationv1beta1.RoleAssignment{
+			APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+			Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+			Spec: &authorizationv1beta1.RoleAssignmentSpec{
+				ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+					PrincipalID:      ptr.To(principalID),
+					RoleDefinitionID: ptr.To("/subscriptions/0000/providers/Microsoft.Authorization/roleDefinitions/" + role),
+					Scope:            ptr.To(scope),
+				},
+			},
+		}
+		if principalType != "" {
+			ra.Spec.ForProvider.PrincipalType = ptr.To(principalType)
+		}
+		return ra
+	}
+
+	cases := map[string]struct {
+		reason   string
+		observed map[resource.Name]resource.ObservedComposed
+		want     map[resource.Name]*authorizationv1beta1.RoleAssignment
+	}{
+		"AccountNotObserved": {
+			reason:   "No role assignments should be returned until the storage account's ID is known.",
+			observed: map[resource.Name]resource.ObservedComposed{},
+			want:     map[resource.Name]*authorizationv1beta1.RoleAssignment{},
+		},
+		"AccountObserved": {
+			reason: "Only role assignments scoped to the storage account should be returned until the containers and identity are observed.",
+			observed: map[resource.Name]resource.ObservedComposed{
+				"account": account,
+			},
+			want: map[resource.Name]*authorizationv1beta1.RoleAssignment{
+				"access-a677846b": roleAssignment(readerPrincipalID, "", "2a2b9908-6ea1-4ae2-8e65-a410df84e7d1", accountID),
+			},
+		},
+		"AllObserved": {
+			reason: "Every grant should be returned once its scope and principal are observed.",
+			observed: map[resource.Name]resource.ObservedComposed{
+				"account":           account,
+				"container-uploads": container,
+				"access-identity":   identity,
+			},
+			want: map[resource.Name]*authorizationv1beta1.RoleAssignment{
+				"access-a677846b":      roleAssignment(readerPrincipalID, "", "2a2b9908-6ea1-4ae2-8e65-a410df84e7d1", accountID),
+				"access-7ad2133f":      roleAssignment(writerPrincipalID, "", "ba92f5b4-2d11-453d-a403-e96b0029c9fe", containerID),
+				"access-identity-role": roleAssignment("principal", "ServicePrincipal", "b7e6dc6d-f1e8-4753-8033-0f276bb0955b", accountID),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := access.RoleAssignments(tc.observed)
+			if err != nil {
+				t.Fatalf("%s\nRoleAssignments(...): unexpected error: %v", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nRoleAssignments(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// defaultACL is the ACL of buckets that don't request one.
+const defaultACL = v1alpha1.XStorageBucketSpecParametersACLPrivate
+
+// A bucketACL is the level of public access to a bucket.
+type bucketACL struct {
+	// ContainerAccessType of the bucket's default container: private, blob
+	// or container.
+	ContainerAccessType string
+
+	// Notice explains a deprecated ACL. Empty unless the XR requests one.
+	Notice string
+}
+
+// aclFrom returns the ACL requested by the supplied parameters. The XRD
+// validates ACLs, but XRs created before it did may still request others,
+// which are rejected rather than silently made private.
+//
+// The compose-bucket-python and compose-bucket-go-templating functions handle
+// ACLs in the same way, and must be kept in sync with this one.
+func aclFrom(params *v1alpha1.XStorageBucketSpecParameters) (bucketACL, error) {
+	switch acl := ptr.Deref(params.ACL, defaultACL); acl {
+	case v1alpha1.XStorageBucketSpecParametersACLPrivate,
+		v1alpha1.XStorageBucketSpecParametersACLBlob,
+		v1alpha1.XStorageBucketSpecParametersACLContainer:
+		return bucketACL{ContainerAccessType: string(acl)}, nil
+	case v1alpha1.XStorageBucketSpecParametersACLPublic:
+		return bucketACL{
+			ContainerAccessType: string(v1alpha1.XStorageBucketSpecParametersACLBlob),
+			Notice:              `acl "public" is deprecated; use "blob" instead`,
+		}, nil
+	default:
+		return bucketACL{}, errors.Errorf("unsupported ACL %q; use one of private, blob, container", acl)
+	}
+}
+
+// allowNestedItemsToBePublic returns true if any of the supplied containers
+// allows public access. Azure rejects public containers unless their storage
+// account allows nested items to be public, so this is derived from the access
+// types of the composed containers rather than from the ACL alone. Private
+// buckets explicitly disallow it, in case it was allowed outside Crossplane.
+func allowNestedItemsToBePublic(containers []bucketContainer) bool {
+	for _, c := range containers {
+		if c.AccessType != string(v1alpha1.XStorageBucketSpecParametersContainersItemAccessTypePrivate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestACLFrom(t *testing.T) {
+	type want struct {
+		acl bucketACL
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		acl    *v1alpha1.XStorageBucketSpecParametersACL
+		want   want
+	}{
+		"NotRequested": {
+			reason: "Buckets should be private unless the XR requests an ACL.",
+			want: want{
+				acl: bucketACL{ContainerAccessType: "private"},
+			},
+		},
+		"Container": {
+			reason: "The container ACL should allow anonymous clients to list the default container.",
+			acl:    ptr.To(v1alpha1.XStorageBucketSpecParametersACLContainer),
+			want: want{
+				acl: bucketACL{ContainerAccessType: "container"},
+			},
+		},
+		"DeprecatedPublic": {
+			reason: "The deprecated public ACL should be treated as blob, with a notice explaining the deprecation.",
+			acl:    ptr.To(v1alpha1.XStorageBucketSpecParametersACLPublic),
+			want: want{
+				acl: bucketACL{ContainerAccessType: "blob", Notice: `acl "public" is deprecated; use "blob" instead`},
+			},
+		},
+		"Unsupported": {
+			reason: "ACLs that aren't part of the enum should be rejected rather than made private, because they're likely typos.",
+			acl:    ptr.To(v1alpha1.XStorageBucketSpecParametersACL("read")),
+			want: want{
+				err: errors.New(`unsupported ACL "read"; use one of private, blob, container`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := aclFrom(&v1alpha1.XStorageBucketSpecParameters{ACL: tc.acl})
+
+			if diff := cmp.Diff(tc.want.acl, got); diff != "" {
+				t.Errorf("%s\naclFrom(...): -want ACL, +got ACL:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\naclFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAllowNestedItemsToBePublic(t *testing.T) {
+	cases := map[string]struct {
+		reason     string
+		containers []bucketContainer
+		want       bool
+	}{
+		"NoContainers": {
+			reason: "Buckets without containers, such as static websites, shouldn't allow public access.",
+			want:   false,
+		},
+		"Private": {
+			reason: "Buckets whose containers are all private shouldn't allow public access.",
+			containers: []bucketContainer{
+				{ResourceName: "container-a", Name: "a", AccessType: "private"},
+				{ResourceName: "container-b", Name: "b", AccessType: "private"},
+			},
+			want: false,
+		},
+		"AnyPublic": {
+			reason: "Buckets with any public container should allow public access, or Azure would reject the container.",
+			containers: []bucketContainer{
+				{ResourceName: "container-a", Name: "a", AccessType: "private"},
+				{ResourceName: "container-b", Name: "b", AccessType: "container"},
+			},
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := allowNestedItemsToBePublic(tc.containers)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nallowNestedItemsToBePublic(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// defaultContainerResourceName is the name of the composed resource for the
+// single container composed when an XR doesn't list its containers.
+const defaultContainerResourceName resource.Name = "container"
+
+// containerResourceNamePrefix prefixes the names of the composed resources for
+// the containers an XR lists. The rest of the name is the container's name, so
+// that each container keeps its composed resource when others are added to or
+// removed from the list.
+const containerResourceNamePrefix = "container-"
+
+// A bucketContainer is a storage container composed for an XR.
+type bucketContainer struct {
+	// ResourceName of the composed Container.
+	ResourceName resource.Name
+
+	// Name of the container in Azure. The default container has no name, and
+	// is named after its composed resource.
+	Name string
+
+	// AccessType of the container: private, blob or container.
+	AccessType string
+
+	// Metadata to assign to the container.
+	Metadata *map[string]string
+}
+
+// containersFrom returns the containers requested by the supplied parameters.
+// If the parameters don't list any containers a single default container is
+// requested, whose access type is the supplied default, unless the bucket is a
+// static website that's served from its own container.
+//
+// Deleting a container deletes its blobs, so once the default container exists
+// it's kept when the XR starts listing its containers or serving a static
+// website. The returned notice explains why it's kept, if it is.
+func containersFrom(params *v1alpha1.XStorageBucketSpecParameters, defaultAccessType string, observed map[resource.Name]resource.ObservedComposed) ([]bucketContainer, string, error) {
+	def := bucketContainer{ResourceName: defaultContainerResourceName, AccessType: defaultAccessType}
+	if params.Containers == nil && params.Website == nil {
+		return []bucketContainer{def}, "", nil
+	}
+
+	containers := make([]bucketContainer, 0, len(ptr.Deref(params.Containers, nil))+1)
+	for _, c := range ptr.Deref(params.Containers, nil) {
+		name := ptr.Deref(c.Name, "")
+		// The XRD validates container names, except for this rule, which
+		// can't be expressed with the regular expressions it supports.
+		if strings.Contains(name, "--") {
+			return nil, "", errors.Errorf("invalid container name %q: must not contain consecutive hyphens", name)
+		}
+		containers = append(containers, bucketContainer{
+			ResourceName: resource.Name(containerResourceNamePrefix + name),
+			Name:         name,
+			AccessType:   string(ptr.Deref(c.AccessType, v1alpha1.XStorageBucketSpecParametersContainersItemAccessTypePrivate)),
+			Metadata:     c.Metadata,
+		})
+	}
+
+	oc, ok := observed[defaultContainerResourceName]
+	if !ok {
+		return containers, "", nil
+	}
+	reason := "listing containers"
+	if params.Containers == nil {
+		reason = "serving a static website"
+	}
+	notice := fmt.Sprintf("Keeping the default container %q: %s would delete it and its blobs", meta.GetExternalName(oc.Resource), reason)
+	return append([]bucketContainer{def}, containers...), notice, nil
+}
+
+// Container returns the composed Container for the bucket container.
+func (c bucketContainer) Container() *storagev1beta1.Container {
+	container := &storagev1beta1.Container{
+		APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+		Spec: &storagev1beta1.ContainerSpec{
+			ForProvider: &storagev1beta1.ContainerSpecForProvider{
+				ContainerAccessType: ptr.To(c.AccessType),
+				Metadata:            c.Metadata,
+				StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}
+	if c.Name != "" {
+		container.Metadata = &metav1.ObjectMeta{
+			Annotations: &map[string]string{
+				meta.AnnotationKeyExternalName: c.Name,
+			},
+		}
+	}
+	return container
+}
+
+// containerURL returns the URL of the named container.
+func containerURL(blobEndpoint, name string) string {
+	return strings.TrimSuffix(blobEndpoint, "/") + "/" + name
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestContainersFrom(t *testing.T) {
+	observedDefault := map[resource.Name]resource.ObservedComposed{
+		defaultContainerResourceName: func() resource.ObservedComposed {
+			c := composed.New()
+			_ = convertViaJSON(c, &storagev1beta1.Container{
+				APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+				Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+				Metadata: &metav1.ObjectMeta{
+					Annotations: &map[string]string{
+						"crossplane.io/external-name": "example-xr-7jxhk",
+					},
+				},
+			})
+			return resource.ObservedComposed{Resource: c}
+		}(),
+	}
+
+	type args struct {
+		params            *v1alpha1.XStorageBucketSpecParameters
+		defaultAccessType string
+		observed          map[resource.Name]resource.ObservedComposed
+	}
+	type want struct {
+		containers []bucketContainer
+		notice     string
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotListed": {
+			reason: "If the XR doesn't list its containers, a single default container should be requested.",
+			args: args{
+				params:            &v1alpha1.XStorageBucketSpecParameters{},
+				defaultAccessType: "blob",
+			},
+			want: want{
+				containers: []bucketContainer{
+					{ResourceName: "container", AccessType: "blob"},
+				},
+			},
+		},
+		"StaticWebsite": {
+			reason: "If the XR is a static website that doesn't list its containers, no containers should be requested.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				defaultAccessType: "blob",
+			},
+			want: want{
+				containers: []bucketContainer{},
+			},
+		},
+		"EmptyList": {
+			reason: "If the XR lists no containers, no containers should be requested.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{},
+				},
+				defaultAccessType: "private",
+			},
+			want: want{
+				containers: []bucketContainer{},
+			},
+		},
+		"Listed": {
+			reason: "Each listed container should be requested under a composed resource name derived from its name.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+						{Name: ptr.To("raw")},
+						{Name: ptr.To("curated"), AccessType: ptr.To(v1alpha1.XStorageBucketSpecParametersContainersItemAccessTypeContainer)},
+					},
+				},
+				defaultAccessType: "blob",
+			},
+			want: want{
+				containers: []bucketContainer{
+					{ResourceName: "container-raw", Name: "raw", AccessType: "private"},
+					{ResourceName: "container-curated", Name: "curated", AccessType: "container"},
+				},
+			},
+		},
+		"ListedDefaultObserved": {
+			reason: "If the XR starts listing its containers once the default container exists, the default container should be kept so that its blobs aren't deleted.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+						{Name: ptr.To("raw")},
+					},
+				},
+				defaultAccessType: "blob",
+				observed:          observedDefault,
+			},
+			want: want{
+				containers: []bucketContainer{
+					{ResourceName: "container", AccessType: "blob"},
+					{ResourceName: "container-raw", Name: "raw", AccessType: "private"},
+				},
+				notice: `Keeping the default container "example-xr-7jxhk": listing containers would delete it and its blobs`,
+			},
+		},
+		"StaticWebsiteDefaultObserved": {
+			reason: "If the XR starts serving a static website once the default container exists, the default container should be kept so that its blobs aren't deleted.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				defaultAccessType: "private",
+				observed:          observedDefault,
+			},
+			want: want{
+				containers: []bucketContainer{
+					{ResourceName: "container", AccessType: "private"},
+				},
+				notice: `Keeping the default container "example-xr-7jxhk": serving a static website would delete it and its blobs`,
+			},
+		},
+		"ConsecutiveHyphens": {
+			reason: "Container names with consecutive hyphens should be rejected.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+						{Name: ptr.To("raw--data")},
+					},
+				},
+			},
+			want: want{
+				err: errors.New(`invalid container name "raw--data": must not contain consecutive hyphens`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, notice, err := containersFrom(tc.args.params, tc.args.defaultAccessType, tc.args.observed)
+
+			if diff := cmp.Diff(tc.want.containers, got); diff != "" {
+				t.Errorf("%s\ncontainersFrom(...): -want containers, +got containers:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.notice, notice); diff != "" {
+				t.Errorf("%s\ncontainersFrom(...): -want notice, +got notice:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ncontainersFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
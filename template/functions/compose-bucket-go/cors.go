@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// corsMethods are the HTTP methods Azure accepts in CORS rules.
+var corsMethods = []string{"DELETE", "GET", "HEAD", "MERGE", "OPTIONS", "PATCH", "POST", "PUT"}
+
+// corsRules returns the blob service CORS rules requested by the supplied
+// parameters, or nil if no CORS rules are requested. Unset headers default to
+// all headers.
+func corsRules(cors *[]v1alpha1.XStorageBucketSpecParametersCorsItem) (*[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemCorsRuleItem, error) {
+	if cors == nil {
+		return nil, nil
+	}
+
+	rules := make([]storagev1beta1.AccountSpecForProviderBlobPropertiesItemCorsRuleItem, 0, len(*cors))
+	for i, c := range *cors {
+		if len(ptr.Deref(c.AllowedOrigins, nil)) == 0 {
+			return nil, errors.Errorf("invalid CORS rule %d: must allow at least one origin", i)
+		}
+		if len(ptr.Deref(c.AllowedMethods, nil)) == 0 {
+			return nil, errors.Errorf("invalid CORS rule %d: must allow at least one method", i)
+		}
+
+		methods := make([]string, 0, len(*c.AllowedMethods))
+		for _, m := range *c.AllowedMethods {
+			m = strings.ToUpper(strings.TrimSpace(m))
+			if !slices.Contains(corsMethods, m) {
+				return nil, errors.Errorf("invalid CORS rule %d: unsupported method %q; use one of %s", i, m, strings.Join(corsMethods, ", "))
+			}
+			methods = append(methods, m)
+		}
+
+		rules = append(rules, storagev1beta1.AccountSpecForProviderBlobPropertiesItemCorsRuleItem{
+			AllowedOrigins:  c.AllowedOrigins,
+			AllowedMethods:  &methods,
+			AllowedHeaders:  ptr.To(ptr.Deref(c.AllowedHeaders, []string{"*"})),
+			ExposedHeaders:  ptr.To(ptr.Deref(c.ExposedHeaders, []string{"*"})),
+			MaxAgeInSeconds: ptr.To(float64(ptr.Deref(c.MaxAgeInSeconds, 0))),
+		})
+	}
+	return &rules, nil
+}
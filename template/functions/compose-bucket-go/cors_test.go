@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestCORSRules(t *testing.T) {
+	type want struct {
+		rules *[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemCorsRuleItem
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		cors   *[]v1alpha1.XStorageBucketSpecParametersCorsItem
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no CORS rules are requested, no CORS rules should be returned.",
+			want:   want{},
+		},
+		"Rules": {
+			reason: "Each CORS rule should be converted to a blob service CORS rule, with methods normalized to upper case.",
+			cors: &[]v1alpha1.XStorageBucketSpecParametersCorsItem{
+				{
+					AllowedOrigins:  &[]string{"https://app.example.com"},
+					AllowedMethods:  &[]string{"get", "PUT"},
+					AllowedHeaders:  &[]string{"x-ms-blob-type", "content-type"},
+					ExposedHeaders:  &[]string{"x-ms-request-id"},
+					MaxAgeInSeconds: ptr.To(3600),
+				},
+				{
+					AllowedOrigins: &[]string{"*"},
+					AllowedMethods: &[]string{"HEAD"},
+				},
+			},
+			want: want{
+				rules: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemCorsRuleItem{
+					{
+						AllowedOrigins:  &[]string{"https://app.example.com"},
+						AllowedMethods:  &[]string{"GET", "PUT"},
+						AllowedHeaders:  &[]string{"x-ms-blob-type", "content-type"},
+						ExposedHeaders:  &[]string{"x-ms-request-id"},
+						MaxAgeInSeconds: ptr.To(3600.0),
+					},
+					{
+						AllowedOrigins:  &[]string{"*"},
+						AllowedMethods:  &[]string{"HEAD"},
+						AllowedHeaders:  &[]string{"*"},
+						ExposedHeaders:  &[]string{"*"},
+						MaxAgeInSeconds: ptr.To(0.0),
+					},
+				},
+			},
+		},
+		"NoOrigins": {
+			reason: "CORS rules that don't allow any origins should be rejected.",
+			cors: &[]v1alpha1.XStorageBucketSpecParametersCorsItem{
+				{AllowedMethods: &[]string{"GET"}},
+			},
+			want: want{
+				err: errors.New("invalid CORS rule 0: must allow at least one origin"),
+			},
+		},
+		"NoMethods": {
+			reason: "CORS rules that don't allow any methods should be rejected.",
+			cors: &[]v1alpha1.XStorageBucketSpecParametersCorsItem{
+				{AllowedOrigins: &[]string{"*"}},
+			},
+			want: want{
+				err: errors.New("invalid CORS rule 0: must allow at least one method"),
+			},
+		},
+		"UnsupportedMethod": {
+			reason: "CORS rules that allow methods Azure doesn't accept should be rejected.",
+			cors: &[]v1alpha1.XStorageBucketSpecParametersCorsItem{
+				{AllowedOrigins: &[]string{"*"}, AllowedMethods: &[]string{"GET"}},
+				{AllowedOrigins: &[]string{"*"}, AllowedMethods: &[]string{"CONNECT"}},
+			},
+			want: want{
+				err: errors.New(`invalid CORS rule 1: unsupported method "CONNECT"; use one of DELETE, GET, HEAD, MERGE, OPTIONS, PATCH, POST, PUT`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := corsRules(tc.cors)
+
+			if diff := cmp.Diff(tc.want.rules, got); diff != "" {
+				t.Errorf("%s\ncorsRules(...): -want rules, +got rules:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ncorsRules(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// blobProperties returns the storage account's blob service properties,
+// covering versioning and the data protection requested by the supplied
+// parameters.
+func blobProperties(params *v1alpha1.XStorageBucketSpecParameters) (storagev1beta1.AccountSpecForProviderBlobPropertiesItem, error) {
+	bp := storagev1beta1.AccountSpecForProviderBlobPropertiesItem{
+		VersioningEnabled: params.Versioning,
+	}
+
+	dp := params.DataProtection
+	if dp == nil {
+		return bp, nil
+	}
+
+	if err := validateDataProtection(dp, ptr.Deref(params.Versioning, false)); err != nil {
+		return storagev1beta1.AccountSpecForProviderBlobPropertiesItem{}, err
+	}
+
+	bp.ChangeFeedEnabled = dp.ChangeFeedEnabled
+	if dp.BlobDeleteRetentionDays != nil {
+		bp.DeleteRetentionPolicy = &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemDeleteRetentionPolicyItem{{
+			Days: days(dp.BlobDeleteRetentionDays),
+		}}
+	}
+	if dp.ContainerDeleteRetentionDays != nil {
+		bp.ContainerDeleteRetentionPolicy = &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemContainerDeleteRetentionPolicyItem{{
+			Days: days(dp.ContainerDeleteRetentionDays),
+		}}
+	}
+	if dp.RestoreDays != nil {
+		bp.RestorePolicy = &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemRestorePolicyItem{{
+			Days: days(dp.RestoreDays),
+		}}
+	}
+
+	return bp, nil
+}
+
+// validateDataProtection returns an error if Azure wouldn't accept the
+// supplied data protection settings. Point-in-time restore replays the change
+// feed over blob versions and soft-deleted blobs, so it depends on all three.
+func validateDataProtection(dp *v1alpha1.XStorageBucketSpecParametersDataProtection, versioning bool) error {
+	if dp.RestoreDays == nil {
+		return nil
+	}
+	if !versioning {
+		return errors.New("point-in-time restore requires versioning")
+	}
+	if !ptr.Deref(dp.ChangeFeedEnabled, false) {
+		return errors.New("point-in-time restore requires the change feed to be enabled")
+	}
+	if dp.BlobDeleteRetentionDays == nil {
+		return errors.New("point-in-time restore requires blobDeleteRetentionDays")
+	}
+	if *dp.RestoreDays >= *dp.BlobDeleteRetentionDays {
+		return errors.Errorf("restoreDays (%d) must be less than blobDeleteRetentionDays (%d)", *dp.RestoreDays, *dp.BlobDeleteRetentionDays)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestBlobProperties(t *testing.T) {
+	type want struct {
+		bp  storagev1beta1.AccountSpecForProviderBlobPropertiesItem
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NoDataProtection": {
+			reason: "If no data protection is requested, only versioning should be configured.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+			},
+			want: want{
+				bp: storagev1beta1.AccountSpecForProviderBlobPropertiesItem{
+					VersioningEnabled: ptr.To(true),
+				},
+			},
+		},
+		"SoftDelete": {
+			reason: "Soft delete shouldn't require versioning or the change feed.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(false),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					BlobDeleteRetentionDays:      ptr.To(7),
+					ContainerDeleteRetentionDays: ptr.To(14),
+				},
+			},
+			want: want{
+				bp: storagev1beta1.AccountSpecForProviderBlobPropertiesItem{
+					VersioningEnabled: ptr.To(false),
+					DeleteRetentionPolicy: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemDeleteRetentionPolicyItem{{
+						Days: ptr.To(7.0),
+					}},
+					ContainerDeleteRetentionPolicy: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemContainerDeleteRetentionPolicyItem{{
+						Days: ptr.To(14.0),
+					}},
+				},
+			},
+		},
+		"PointInTimeRestore": {
+			reason: "Point-in-time restore should be configured alongside the data protection it depends on.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					BlobDeleteRetentionDays: ptr.To(30),
+					ChangeFeedEnabled:       ptr.To(true),
+					RestoreDays:             ptr.To(29),
+				},
+			},
+			want: want{
+				bp: storagev1beta1.AccountSpecForProviderBlobPropertiesItem{
+					VersioningEnabled: ptr.To(true),
+					ChangeFeedEnabled: ptr.To(true),
+					DeleteRetentionPolicy: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemDeleteRetentionPolicyItem{{
+						Days: ptr.To(30.0),
+					}},
+					RestorePolicy: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItemRestorePolicyItem{{
+						Days: ptr.To(29.0),
+					}},
+				},
+			},
+		},
+		"RestoreWithoutVersioning": {
+			reason: "Point-in-time restore should be rejected unless versioning is enabled.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(false),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					BlobDeleteRetentionDays: ptr.To(30),
+					ChangeFeedEnabled:       ptr.To(true),
+					RestoreDays:             ptr.To(7),
+				},
+			},
+			want: want{
+				err: errors.New("point-in-time restore requires versioning"),
+			},
+		},
+		"RestoreWithoutChangeFeed": {
+			reason: "Point-in-time restore should be rejected unless the change feed is enabled.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					BlobDeleteRetentionDays: ptr.To(30),
+					RestoreDays:             ptr.To(7),
+				},
+			},
+			want: want{
+				err: errors.New("point-in-time restore requires the change feed to be enabled"),
+			},
+		},
+		"RestoreWithoutSoftDelete": {
+			reason: "Point-in-time restore should be rejected unless blob soft delete is enabled.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					ChangeFeedEnabled: ptr.To(true),
+					RestoreDays:       ptr.To(7),
+				},
+			},
+			want: want{
+				err: errors.New("point-in-time restore requires blobDeleteRetentionDays"),
+			},
+		},
+		"RestoreOutlivesSoftDelete": {
+			reason: "Point-in-time restore windows that aren't shorter than blob soft delete retention should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				DataProtection: &v1alpha1.XStorageBucketSpecParametersDataProtection{
+					BlobDeleteRetentionDays: ptr.To(7),
+					ChangeFeedEnabled:       ptr.To(true),
+					RestoreDays:             ptr.To(7),
+				},
+			},
+			want: want{
+				err: errors.New("restoreDays (7) must be less than blobDeleteRetentionDays (7)"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := blobProperties(tc.params)
+
+			if diff := cmp.Diff(tc.want.bp, got); diff != "" {
+				t.Errorf("%s\nblobProperties(...): -want properties, +got properties:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nblobProperties(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
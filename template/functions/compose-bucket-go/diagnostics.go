@@ -0,0 +1,190 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	insightsv1beta1 "dev.upbound.io/models/io/upbound/azure/insights/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Names of the composed diagnostic settings. Azure only emits metrics for the
+// storage account itself; the logs of blob requests come from its blob
+// service.
+const (
+	accountDiagnosticsResourceName resource.Name = "account-diagnostics"
+	blobDiagnosticsResourceName    resource.Name = "blob-diagnostics"
+)
+
+// diagnosticSettingName is the name of the composed diagnostic settings in
+// Azure. It need only be unique among the settings of each resource.
+const diagnosticSettingName = "crossplane"
+
+var (
+	// diagnosticsLogCategories are the categories of blob service logs.
+	diagnosticsLogCategories = []string{"StorageDelete", "StorageRead", "StorageWrite"}
+
+	// diagnosticsMetrics are the categories of storage metrics.
+	diagnosticsMetrics = []string{"Capacity", "Transaction"}
+
+	// defaultDiagnosticsMetrics are sent unless the XR requests other metrics.
+	defaultDiagnosticsMetrics = []string{"Transaction"}
+)
+
+var (
+	logAnalyticsWorkspaceIDPattern     = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.OperationalInsights/workspaces/[^/]+$`)
+	storageAccountIDPattern            = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Storage/storageAccounts/[^/]+$`)
+	eventHubAuthorizationRuleIDPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.EventHub/namespaces/[^/]+/authorizationRules/[^/]+$`)
+)
+
+// diagnostics are where the storage account's logs and metrics are sent.
+type diagnostics struct {
+	// LogAnalyticsWorkspaceID is the Azure resource ID of the Log Analytics
+	// workspace logs and metrics are sent to.
+	LogAnalyticsWorkspaceID string
+
+	// StorageAccountID is the Azure resource ID of a storage account logs
+	// and metrics are archived to. Empty if they aren't archived.
+	StorageAccountID string
+
+	// EventHubAuthorizationRuleID is the Azure resource ID of the Event Hubs
+	// namespace authorization rule used to stream logs and metrics. Empty if
+	// they aren't streamed.
+	EventHubAuthorizationRuleID string
+
+	// EventHubName is the event hub logs and metrics are streamed to. Empty
+	// if they're streamed to the namespace's default event hub.
+	EventHubName string
+
+	// LogCategories of blob service logs to send.
+	LogCategories []string
+
+	// Metrics to send.
+	Metrics []string
+}
+
+// diagnosticsFrom returns the diagnostics requested by the supplied
+// parameters, or nil if no diagnostics are requested. All log categories are
+// sent unless the XR requests specific categories.
+func diagnosticsFrom(d *v1alpha1.XStorageBucketSpecParametersDiagnostics) (*diagnostics, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	diag := &diagnostics{
+		LogAnalyticsWorkspaceID: ptr.Deref(d.LogAnalyticsWorkspaceID, ""),
+		StorageAccountID:        ptr.Deref(d.StorageAccountID, ""),
+		LogCategories:           slices.Clone(diagnosticsLogCategories),
+		Metrics:                 slices.Clone(defaultDiagnosticsMetrics),
+	}
+	if !logAnalyticsWorkspaceIDPattern.MatchString(diag.LogAnalyticsWorkspaceID) {
+		return nil, errors.Errorf("invalid Log Analytics workspace ID %q: must be the resource ID of a Log Analytics workspace", diag.LogAnalyticsWorkspaceID)
+	}
+	if diag.StorageAccountID != "" && !storageAccountIDPattern.MatchString(diag.StorageAccountID) {
+		return nil, errors.Errorf("invalid storage account ID %q: must be the resource ID of a storage account", diag.StorageAccountID)
+	}
+	if eh := d.EventHub; eh != nil {
+		diag.EventHubAuthorizationRuleID = ptr.Deref(eh.AuthorizationRuleID, "")
+		diag.EventHubName = ptr.Deref(eh.Name, "")
+		if !eventHubAuthorizationRuleIDPattern.MatchString(diag.EventHubAuthorizationRuleID) {
+			return nil, errors.Errorf("invalid Event Hubs authorization rule ID %q: must be the resource ID of an Event Hubs namespace authorization rule", diag.EventHubAuthorizationRuleID)
+		}
+	}
+
+	if d.LogCategories != nil {
+		diag.LogCategories = make([]string, 0, len(*d.LogCategories))
+		for _, c := range *d.LogCategories {
+			if !slices.Contains(diagnosticsLogCategories, string(c)) {
+				return nil, errors.Errorf("unsupported log category %q; use one of %s", c, strings.Join(diagnosticsLogCategories, ", "))
+			}
+			diag.LogCategories = append(diag.LogCategories, string(c))
+		}
+	}
+	if d.Metrics != nil {
+		diag.Metrics = make([]string, 0, len(*d.Metrics))
+		for _, m := range *d.Metrics {
+			if !slices.Contains(diagnosticsMetrics, string(m)) {
+				return nil, errors.Errorf("unsupported metric %q; use one of %s", m, strings.Join(diagnosticsMetrics, ", "))
+			}
+			diag.Metrics = append(diag.Metrics, string(m))
+		}
+	}
+	if len(diag.LogCategories) == 0 && len(diag.Metrics) == 0 {
+		return nil, errors.New("must send at least one log category or metric")
+	}
+
+	// Sort the categories, so that reordering them doesn't update the
+	// composed resources.
+	slices.Sort(diag.LogCategories)
+	diag.LogCategories = slices.Compact(diag.LogCategories)
+	slices.Sort(diag.Metrics)
+	diag.Metrics = slices.Compact(diag.Metrics)
+
+	return diag, nil
+}
+
+// DiagnosticSettings returns the composed diagnostic settings of the supplied
+// storage account and its blob service. The account's diagnostic setting is
+// omitted if no metrics are requested, because it only emits metrics.
+func (d diagnostics) DiagnosticSettings(accountID string) map[resource.Name]*insightsv1beta1.MonitorDiagnosticSetting {
+	metrics := make([]insightsv1beta1.MonitorDiagnosticSettingSpecForProviderMetricItem, 0, len(d.Metrics))
+	for _, m := range d.Metrics {
+		metrics = append(metrics, insightsv1beta1.MonitorDiagnosticSettingSpecForProviderMetricItem{
+			Category: ptr.To(m),
+			Enabled:  ptr.To(true),
+		})
+	}
+
+	settings := make(map[resource.Name]*insightsv1beta1.MonitorDiagnosticSetting)
+	if len(metrics) > 0 {
+		account := d.diagnosticSetting(accountID)
+		account.Spec.ForProvider.Metric = &metrics
+		settings[accountDiagnosticsResourceName] = account
+	}
+
+	blob := d.diagnosticSetting(accountID + "/blobServices/default")
+	if len(metrics) > 0 {
+		blob.Spec.ForProvider.Metric = &metrics
+	}
+	if len(d.LogCategories) > 0 {
+		logs := make([]insightsv1beta1.MonitorDiagnosticSettingSpecForProviderEnabledLogItem, 0, len(d.LogCategories))
+		for _, c := range d.LogCategories {
+			logs = append(logs, insightsv1beta1.MonitorDiagnosticSettingSpecForProviderEnabledLogItem{Category: ptr.To(c)})
+		}
+		blob.Spec.ForProvider.EnabledLog = &logs
+	}
+	settings[blobDiagnosticsResourceName] = blob
+
+	return settings
+}
+
+// diagnosticSetting returns a composed diagnostic setting that sends the
+// supplied resource's logs and metrics to the diagnostics' destinations.
+func (d diagnostics) diagnosticSetting(targetID string) *insightsv1beta1.MonitorDiagnosticSetting {
+	ds := &insightsv1beta1.MonitorDiagnosticSetting{
+		APIVersion: ptr.To(insightsv1beta1.MonitorDiagnosticSettingAPIVersioninsightsAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(insightsv1beta1.MonitorDiagnosticSettingKindMonitorDiagnosticSetting),
+		Spec: &insightsv1beta1.MonitorDiagnosticSettingSpec{
+			ForProvider: &insightsv1beta1.MonitorDiagnosticSettingSpecForProvider{
+				Name:                    ptr.To(diagnosticSettingName),
+				TargetResourceID:        ptr.To(targetID),
+				LogAnalyticsWorkspaceID: ptr.To(d.LogAnalyticsWorkspaceID),
+			},
+		},
+	}
+	if d.StorageAccountID != "" {
+		ds.Spec.ForProvider.StorageAccountID = ptr.To(d.StorageAccountID)
+	}
+	if d.EventHubAuthorizationRuleID != "" {
+		ds.Spec.ForProvider.EventhubAuthorizationRuleID = ptr.To(d.EventHubAuthorizationRuleID)
+	}
+	if d.EventHubName != "" {
+		ds.Spec.ForProvider.EventhubName = ptr.To(d.EventHubName)
+	}
+	return ds
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	insightsv1beta1 "dev.upbound.io/models/io/upbound/azure/insights/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+const (
+	workspaceID = "/subscriptions/0000/resourceGroups/monitoring/providers/Microsoft.OperationalInsights/workspaces/audit"
+	archiveID   = "/subscriptions/0000/resourceGroups/monitoring/providers/Microsoft.Storage/storageAccounts/auditarchive"
+	eventHubID  = "/subscriptions/0000/resourceGroups/monitoring/providers/Microsoft.EventHub/namespaces/audit/authorizationRules/RootManageSharedAccessKey"
+)
+
+func TestDiagnosticsFrom(t *testing.T) {
+	type want struct {
+		diag *diagnostics
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		d      *v1alpha1.XStorageBucketSpecParametersDiagnostics
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no diagnostics are requested, no diagnostics should be returned.",
+			want:   want{},
+		},
+		"Defaults": {
+			reason: "All log categories and transaction metrics should be sent by default.",
+			d: &v1alpha1.XStorageBucketSpecParametersDiagnostics{
+				LogAnalyticsWorkspaceID: ptr.To(workspaceID),
+			},
+			want: want{
+				diag: &diagnostics{
+					LogAnalyticsWorkspaceID: workspaceID,
+					LogCategories:           []string{"StorageDelete", "StorageRead", "StorageWrite"},
+					Metrics:                 []string{"Transaction"},
+				},
+			},
+		},
+		"AllDestinations": {
+			reason: "The requested categories should be sorted and sent to every requested destination.",
+			d: &v1alpha1.XStorageBucketSpecParametersDiagnostics{
+				LogAnalyticsWorkspaceID: ptr.To(workspaceID),
+				StorageAccountID:        ptr.To(archiveID),
+				EventHub: &v1alpha1.XStorageBucketSpecParametersDiagnosticsEventHub{
+					AuthorizationRuleID: ptr.To(eventHubID),
+					Name:                ptr.To("storage"),
+				},
+				LogCategories: &[]v1alpha1.XStorageBucketSpecParametersDiagnosticsLogCategoriesItem{"StorageWrite", "StorageDelete"},
+				Metrics:       &[]v1alpha1.XStorageBucketSpecParametersDiagnosticsMetricsItem{"Transaction", "Capacity"},
+			},
+			want: want{
+				diag: &diagnostics{
+					LogAnalyticsWorkspaceID:     workspaceID,
+					StorageAccountID:            archiveID,
+					EventHubAuthorizationRuleID: eventHubID,
+					EventHubName:                "storage",
+					LogCategories:               []string{"StorageDelete", "StorageWrite"},
+					Metrics:                     []string{"Capacity", "Transaction"},
+				},
+			},
+		},
+		"InvalidWorkspaceID": {
+			reason: "Diagnostics should be rejected unless they're sent to a Log Analytics workspace.",
+			d: &v1alpha1.XStorageBucketSpecParametersDiagnostics{
+				LogAnalyticsWorkspaceID: ptr.To(archiveID),
+			},
+			want: want{
+				err: errors.Errorf("invalid Log Analytics workspace ID %q: must be the resource ID of a Log Analytics workspace", archiveID),
+			},
+		},
+		"UnsupportedLogCategory": {
+			reason: "Log categories Azure doesn't emit for blob services should be rejected.",
+			d: &v1alpha1.XStorageBucketSpecParametersDiagnostics{
+				LogAnalyticsWorkspaceID: ptr.To(workspaceID),
+				LogCategories:           &[]v1alpha1.XStorageBucketSpecParametersDiagnosticsLogCategoriesItem{"AuditEvent"},
+			},
+			want: want{
+				err: errors.New(`unsupported log category "AuditEvent"; use one of StorageDelete, StorageRead, StorageWrite`),
+			},
+		},
+		"NothingToSend": {
+			reason: "Diagnostics that send no logs or metrics should be rejected.",
+			d: &v1alpha1.XStorageBucketSpecParametersDiagnostics{
+				LogAnalyticsWorkspaceID: ptr.To(workspaceID),
+				LogCategories:           &[]v1alpha1.XStorageBucketSpecParametersDiagnosticsLogCategoriesItem{},
+				Metrics:                 &[]v1alpha1.XStorageBucketSpecParametersDiagnosticsMetricsItem{},
+			},
+			want: want{
+				err: errors.New("must send at least one log category or metric"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := diagnosticsFrom(tc.d)
+
+			if diff := cmp.Diff(tc.want.diag, got); diff != "" {
+				t.Errorf("%s\ndiagnosticsFrom(...): -want diagnostics, +got diagnostics:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ndiagnosticsFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDiagnosticSettings(t *testing.T) {
+	accountID := "/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.Storage/storageAccounts/examplexre848635a"
+
+	setting := func(targetID string) *insightsv1beta1.MonitorDiagnosticSetting {
+		return &insightsv1beta1.MonitorDiagnosticSetting{
+			APIVersion: ptr.To(insightsv1beta1.MonitorDiagnosticSettingAPIVersioninsightsAzureUpboundIoV1Beta1),
+			Kind:       ptr.To(insightsv1beta1.MonitorDiagnosticSettingKindMonitorDiagnosticSetting),
+			Spec: &insightsv1beta1.MonitorDiagnosticSettingSpec{
+				ForProvider: &insightsv1beta1.MonitorDiagnosticSettingSpecForProvider{
+					Name:                    ptr.To("crossplane"),
+					TargetResourceID:        ptr.To(targetID),
+					LogAnalyticsWorkspaceID: ptr.To(workspaceID),
+				},
+			},
+		}
+	}
+	metrics := &[]insightsv1beta1.MonitorDiagnosticSettingSpecForProviderMetricItem{
+		{Category: ptr.To("Transaction"), Enabled: ptr.To(true)},
+	}
+	logs := &[]insightsv1beta1.MonitorDiagnosticSettingSpecForProviderEnabledLogItem{
+		{Category: ptr.To("StorageWrite")},
+	}
+
+	account := setting(accountID)
+	account.Spec.ForProvider.Metric = metrics
+
+	blob := setting(accountID + "/blobServices/default")
+	blob.Spec.ForProvider.Metric = metrics
+	blob.Spec.ForProvider.EnabledLog = logs
+
+	blobLogsOnly := setting(accountID + "/blobServices/default")
+	blobLogsOnly.Spec.ForProvider.EnabledLog = logs
+
+	cases := map[string]struct {
+		reason string
+		diag   diagnostics
+		want   map[resource.Name]*insightsv1beta1.MonitorDiagnosticSetting
+	}{
+		"LogsAndMetrics": {
+			reason: "Metrics should be sent for the storage account and its blob service, and logs for its blob service.",
+			diag: diagnostics{
+				LogAnalyticsWorkspaceID: workspaceID,
+				LogCategories:           []string{"StorageWrite"},
+				Metrics:                 []string{"Transaction"},
+			},
+			want: map[resource.Name]*insightsv1beta1.MonitorDiagnosticSetting{
+				"account-diagnostics": account,
+				"blob-diagnostics":    blob,
+			},
+		},
+		"LogsOnly": {
+			reason: "If no metrics are requested, the storage account shouldn't have a diagnostic setting.",
+			diag: diagnostics{
+				LogAnalyticsWorkspaceID: workspaceID,
+				LogCategories:           []string{"StorageWrite"},
+			},
+			want: map[resource.Name]*insightsv1beta1.MonitorDiagnosticSetting{
+				"blob-diagnostics": blobLogsOnly,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.diag.DiagnosticSettings(accountID)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nDiagnosticSettings(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	authorizationv1beta1 "dev.upbound.io/models/io/upbound/azure/authorization/v1beta1"
+	managedidentityv1beta1 "dev.upbound.io/models/io/upbound/azure/managedidentity/v1beta1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Names of the composed resources used to encrypt a storage account with a
+// customer-managed key.
+const (
+	identityResourceName           resource.Name = "identity"
+	keyAccessResourceName          resource.Name = "key-access"
+	customerManagedKeyResourceName resource.Name = "customer-managed-key"
+)
+
+// keyAccessRole is the built-in role that lets a storage account's identity
+// wrap and unwrap its encryption key.
+const keyAccessRole = "Key Vault Crypto Service Encryption User"
+
+// keyVaultIDPattern matches the Azure resource ID of a Key Vault, capturing
+// the vault's name.
+var keyVaultIDPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.KeyVault/vaults/([^/]+)$`)
+
+// A customerManagedKey is the Key Vault key used to encrypt a storage account.
+type customerManagedKey struct {
+	// KeyVaultID is the Azure resource ID of the Key Vault holding the key.
+	KeyVaultID string
+
+	// KeyName is the name of the key.
+	KeyName string
+
+	// KeyVersion is the version of the key. Empty if the storage account
+	// should automatically use the key's latest version.
+	KeyVersion string
+}
+
+// customerManagedKeyFrom returns the customer-managed key requested by the
+// supplied parameters, or nil if the storage account should be encrypted with
+// a Microsoft-managed key.
+func customerManagedKeyFrom(encryption *v1alpha1.XStorageBucketSpecParametersEncryption) (*customerManagedKey, error) {
+	if encryption == nil {
+		return nil, nil
+	}
+
+	vaultID := ptr.Deref(encryption.KeyVaultID, "")
+	m := keyVaultIDPattern.FindStringSubmatch(vaultID)
+	if m == nil {
+		return nil, errors.Errorf("invalid Key Vault ID %q: must be the resource ID of a Key Vault", vaultID)
+	}
+	vaultName := m[1]
+
+	keyID := ptr.Deref(encryption.KeyID, "")
+	k, vault, err := parseKeyID(keyID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key ID %q", keyID)
+	}
+	if !strings.EqualFold(vault, vaultName) {
+		return nil, errors.Errorf("key ID %q isn't a key in Key Vault %q", keyID, vaultName)
+	}
+	k.KeyVaultID = vaultID
+
+	return k, nil
+}
+
+// parseKeyID parses a Key Vault key ID like
+// https://example.vault.azure.net/keys/name/version, returning the key and
+// the name of the vault that holds it. The version is optional.
+func parseKeyID(id string) (*customerManagedKey, string, error) {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, "", errors.New("must be an https URL")
+	}
+	vault, domain, _ := strings.Cut(u.Hostname(), ".")
+	if vault == "" || !strings.HasPrefix(domain, "vault.") {
+		return nil, "", errors.New("must be a Key Vault URL")
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != "keys" || slices.Contains(segments, "") {
+		return nil, "", errors.New("path must be /keys/<name> or /keys/<name>/<version>")
+	}
+
+	k := &customerManagedKey{KeyName: segments[1]}
+	if len(segments) == 3 {
+		k.KeyVersion = segments[2]
+	}
+	return k, vault, nil
+}
+
+// Identity returns the composed user-assigned identity the storage account
+// uses to access its key.
+func (k customerManagedKey) Identity(location *string, group bucketResourceGroup, tags map[string]string) *managedidentityv1beta1.UserAssignedIdentity {
+	return userAssignedIdentity(location, group, tags)
+}
+
+// RoleAssignment returns the composed role assignment that grants the
+// supplied identity access to the key. It's scoped to the key rather than its
+// vault, so that the identity can't use the vault's other keys. Key Vault
+// grants access to every version of the key.
+func (k customerManagedKey) RoleAssignment(principalID string) *authorizationv1beta1.RoleAssignment {
+	return &authorizationv1beta1.RoleAssignment{
+		APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+		Spec: &authorizationv1beta1.RoleAssignmentSpec{
+			ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+				PrincipalID:        ptr.To(principalID),
+				PrincipalType:      ptr.To("ServicePrincipal"),
+				RoleDefinitionName: ptr.To(keyAccessRole),
+				Scope:              ptr.To(k.KeyVaultID + "/keys/" + k.KeyName),
+			},
+		},
+	}
+}
+
+// AccountCustomerManagedKey returns the composed AccountCustomerManagedKey
+// that encrypts the storage account with the key, accessed as the supplied
+// identity.
+func (k customerManagedKey) AccountCustomerManagedKey(identityID string) *storagev1beta1.AccountCustomerManagedKey {
+	cmk := &storagev1beta1.AccountCustomerManagedKey{
+		APIVersion: ptr.To(storagev1beta1.AccountCustomerManagedKeyAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.AccountCustomerManagedKeyKindAccountCustomerManagedKey),
+		Spec: &storagev1beta1.AccountCustomerManagedKeySpec{
+			ForProvider: &storagev1beta1.AccountCustomerManagedKeySpecForProvider{
+				KeyVaultID:             ptr.To(k.KeyVaultID),
+				KeyName:                ptr.To(k.KeyName),
+				UserAssignedIdentityID: ptr.To(identityID),
+				StorageAccountIDSelector: &storagev1beta1.AccountCustomerManagedKeySpecForProviderStorageAccountIDSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}
+	if k.KeyVersion != "" {
+		cmk.Spec.ForProvider.KeyVersion = ptr.To(k.KeyVersion)
+	}
+	return cmk
+}
+
+// observedIdentity returns the Azure resource ID and principal ID of the
+// observed user-assigned identity. Each is empty until it has been observed.
+func observedIdentity(observed map[resource.Name]resource.ObservedComposed) (id, principalID string, err error) {
+	ap, err := observedIdentityStatus(observed, identityResourceName)
+	if err != nil || ap == nil {
+		return "", "", err
+	}
+	return ptr.Deref(ap.ID, ""), ptr.Deref(ap.PrincipalID, ""), nil
+}
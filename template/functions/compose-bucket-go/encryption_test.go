@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	authorizationv1beta1 "dev.upbound.io/models/io/upbound/azure/authorization/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestCustomerManagedKeyFrom(t *testing.T) {
+	vaultID := "/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault"
+
+	type want struct {
+		key *customerManagedKey
+		err error
+	}
+
+	cases := map[string]struct {
+		reason     string
+		encryption *v1alpha1.XStorageBucketSpecParametersEncryption
+		want       want
+	}{
+		"MicrosoftManagedKey": {
+			reason: "If no encryption is requested, no customer-managed key should be returned.",
+			want:   want{},
+		},
+		"VersionedKey": {
+			reason: "A versioned key ID should pin the key's version.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To(vaultID),
+				KeyID:      ptr.To("https://example-vault.vault.azure.net/keys/storage/0123456789abcdef"),
+			},
+			want: want{
+				key: &customerManagedKey{KeyVaultID: vaultID, KeyName: "storage", KeyVersion: "0123456789abcdef"},
+			},
+		},
+		"VersionlessKey": {
+			reason: "A versionless key ID should leave the key's version unset, so that the account uses the latest version.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To(vaultID),
+				KeyID:      ptr.To("https://Example-Vault.vault.azure.net/keys/storage/"),
+			},
+			want: want{
+				key: &customerManagedKey{KeyVaultID: vaultID, KeyName: "storage"},
+			},
+		},
+		"InvalidKeyVaultID": {
+			reason: "Key Vault IDs that aren't Key Vault resource IDs should be rejected.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To("example-vault"),
+				KeyID:      ptr.To("https://example-vault.vault.azure.net/keys/storage"),
+			},
+			want: want{
+				err: errors.New(`invalid Key Vault ID "example-vault": must be the resource ID of a Key Vault`),
+			},
+		},
+		"NotKeyVaultURL": {
+			reason: "Key IDs that aren't Key Vault URLs should be rejected.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To(vaultID),
+				KeyID:      ptr.To("https://example-vault.blob.core.windows.net/keys/storage"),
+			},
+			want: want{
+				err: errors.Wrap(errors.New("must be a Key Vault URL"), `invalid key ID "https://example-vault.blob.core.windows.net/keys/storage"`),
+			},
+		},
+		"NotKeyPath": {
+			reason: "Key IDs that don't identify a key should be rejected.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To(vaultID),
+				KeyID:      ptr.To("https://example-vault.vault.azure.net/secrets/storage"),
+			},
+			want: want{
+				err: errors.Wrap(errors.New("path must be /keys/<name> or /keys/<name>/<version>"), `invalid key ID "https://example-vault.vault.azure.net/secrets/storage"`),
+			},
+		},
+		"KeyInOtherVault": {
+			reason: "Keys that aren't in the supplied Key Vault should be rejected.",
+			encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+				KeyVaultID: ptr.To(vaultID),
+				KeyID:      ptr.To("https://other-vault.vault.azure.net/keys/storage"),
+			},
+			want: want{
+				err: errors.New(`key ID "https://other-vault.vault.azure.net/keys/storage" isn't a key in Key Vault "example-vault"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := customerManagedKeyFrom(tc.encryption)
+
+			if diff := cmp.Diff(tc.want.key, got); diff != "" {
+				t.Errorf("%s\ncustomerManagedKeyFrom(...): -want key, +got key:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ncustomerManagedKeyFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestKeyAccessRoleAssignment(t *testing.T) {
+	k := customerManagedKey{
+		KeyVaultID: "/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault",
+		KeyName:    "storage",
+		KeyVersion: "0123456789abcdef",
+	}
+
+	want := &authorizationv1beta1.RoleAssignment{
+		APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+		Spec: &authorizationv1beta1.RoleAssignmentSpec{
+			ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+				PrincipalID:        ptr.To("11111111-2222-3333-4444-555555555555"),
+				PrincipalType:      ptr.To("ServicePrincipal"),
+				RoleDefinitionName: ptr.To("Key Vault Crypto Service Encryption User"),
+				Scope:              ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault/keys/storage"),
+			},
+		},
+	}
+
+	got := k.RoleAssignment("11111111-2222-3333-4444-555555555555")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("The identity should only be granted access to the key, not to the vault's other keys.\nRoleAssignment(...): -want, +got:\n%s", diff)
+	}
+}
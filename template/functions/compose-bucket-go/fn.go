@@ -3,28 +3,77 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"strings"
 
 	"dev.upbound.io/models/com/example/platform/v1alpha1"
 	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	networkv1beta1 "dev.upbound.io/models/io/upbound/azure/network/v1beta1"
 	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
 	azv1beta1 "dev.upbound.io/models/io/upbound/azure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/function-sdk-go/errors"
 	"github.com/crossplane/function-sdk-go/logging"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
 	"github.com/crossplane/function-sdk-go/request"
 	"github.com/crossplane/function-sdk-go/resource"
 	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/resource/composite"
 	"github.com/crossplane/function-sdk-go/response"
 	"k8s.io/utils/ptr"
 )
 
+// The Composed condition reports which stage of composition an XStorageBucket
+// is in. Each stage waits for the resources of the previous stage to become
+// ready, so that selectors always resolve on the first attempt.
+const (
+	conditionTypeComposed = "Composed"
+
+	stageResourceGroup = "WaitingForResourceGroup"
+	stageIdentity      = "WaitingForIdentity"
+	stageAccount       = "WaitingForAccount"
+	stageKeyAccess     = "WaitingForKeyAccess"
+	stageComplete      = "AllResourcesComposed"
+)
+
+// Keys of the XR's connection details. They're named after the environment
+// variables read by the Azure CLI and SDKs, so that consumers can load the
+// connection secret straight into their environment.
+const (
+	connectionKeyAccountName      = "AZURE_STORAGE_ACCOUNT"
+	connectionKeyAccessKey        = "AZURE_STORAGE_KEY"
+	connectionKeyBlobEndpoint     = "AZURE_STORAGE_BLOB_ENDPOINT"
+	connectionKeyContainerName    = "AZURE_STORAGE_CONTAINER"
+	connectionKeyConnectionString = "AZURE_STORAGE_CONNECTION_STRING"
+)
+
+// Keys of the storage account's connection details that we publish. The
+// provider publishes sensitive attributes with an "attribute." prefix.
+const (
+	accountConnectionKeyPrimaryAccessKey        = "attribute.primary_access_key"
+	accountConnectionKeyPrimaryConnectionString = "attribute.primary_connection_string"
+)
+
+// defaultConnectionSecretNamespace is the namespace the storage account writes
+// its connection secret to, unless the XR writes its own connection secret
+// elsewhere. It's the namespace of a standard Crossplane install.
+const defaultConnectionSecretNamespace = "crossplane-system"
+
 // Function is your composition function.
 type Function struct {
 	fnv1.UnimplementedFunctionRunnerServiceServer
 
-	log logging.Logger
+	log     logging.Logger
+	regions regionPolicy
+
+	// requiredTags must be set by every XR.
+	requiredTags []string
+
+	// secretNamespace is the namespace composed resources write their
+	// connection secrets to if the XR doesn't write its own. Empty to use
+	// defaultConnectionSecretNamespace.
+	secretNamespace string
 }
 
 // RunFunction runs the Function.
@@ -50,6 +99,214 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 		return rsp, nil
 	}
 
+	// Compose resources in the region's name, even if the XR requests it by
+	// its display name.
+	location, err := f.regions.Location(*params.Location)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid location parameter"))
+		return rsp, nil
+	}
+	params.Location = &location
+
+	providerConfig, err := providerConfigNameFrom(req, params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot determine ProviderConfig"))
+		return rsp, nil
+	}
+	if providerConfig != "" {
+		// Crossplane calls the function again once it has looked up the
+		// required ProviderConfig, so there's nothing to compose until then.
+		pcs, ok, err := requireProviderConfig(req, rsp, providerConfig)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot require ProviderConfig"))
+			return rsp, nil
+		}
+		if !ok {
+			return rsp, nil
+		}
+		// A fatal result stops Crossplane applying the desired state, so
+		// existing resources are left as they are rather than deleted.
+		if len(pcs) == 0 {
+			response.Fatal(rsp, errors.Errorf("ProviderConfig %q doesn't exist", providerConfig))
+			return rsp, nil
+		}
+	}
+
+	sku := skuFrom(params)
+	if err := sku.validate(); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "unsupported storage account configuration"))
+		return rsp, nil
+	}
+
+	cmk, err := customerManagedKeyFrom(params.Encryption)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid encryption parameter"))
+		return rsp, nil
+	}
+
+	bp, err := blobProperties(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid dataProtection parameter"))
+		return rsp, nil
+	}
+	bp.CorsRule, err = corsRules(params.Cors)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid cors parameter"))
+		return rsp, nil
+	}
+
+	website, err := staticWebsite(params, sku)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid website parameter"))
+		return rsp, nil
+	}
+
+	imm, err := immutabilityFrom(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid immutability parameter"))
+		return rsp, nil
+	}
+	if imm != nil && imm.Notice != "" {
+		response.Warning(rsp, errors.New(imm.Notice)).TargetCompositeAndClaim()
+	}
+
+	policy, err := managementPolicy(params.Lifecycle, sku)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid lifecycle parameter"))
+		return rsp, nil
+	}
+
+	wi, err := workloadIdentityFrom(params.WorkloadIdentity)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid workloadIdentity parameter"))
+		return rsp, nil
+	}
+
+	tags, err := tagsFrom(&xr, f.requiredTags)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid tags parameter"))
+		return rsp, nil
+	}
+
+	diag, err := diagnosticsFrom(params.Diagnostics)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid diagnostics parameter"))
+		return rsp, nil
+	}
+
+	network, err := accountNetworkFrom(params.Network)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid network parameter"))
+		return rsp, nil
+	}
+
+	endpoint, err := privateEndpointFrom(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid privateEndpoint parameter"))
+		return rsp, nil
+	}
+	// Private buckets are only reachable through their private endpoint.
+	if endpoint != nil {
+		network.PublicNetworkAccessEnabled = ptr.To(false)
+	}
+
+	observedComposed, err := request.GetObservedComposedResources(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot get observed composed resources"))
+		return rsp, nil
+	}
+
+	// Locked immutability policies can only be tightened. We refuse to
+	// compose anything rather than ask Azure to loosen them.
+	locked, err := observedLockedImmutability(observedComposed)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot get observed immutability policies"))
+		return rsp, nil
+	}
+	if err := validateImmutabilityChange(imm, locked); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "refusing to loosen immutability policy"))
+		return rsp, nil
+	}
+
+	group, err := resourceGroupFrom(params, observedComposed)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid resourceGroupName parameter"))
+		return rsp, nil
+	}
+	if group.Notice != "" {
+		response.Warning(rsp, errors.New(group.Notice)).TargetCompositeAndClaim()
+	}
+
+	acl, err := aclFrom(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid acl parameter"))
+		return rsp, nil
+	}
+	if acl.Notice != "" {
+		response.Warning(rsp, errors.New(acl.Notice)).TargetCompositeAndClaim()
+	}
+
+	containers, notice, err := containersFrom(params, acl.ContainerAccessType, observedComposed)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid containers parameter"))
+		return rsp, nil
+	}
+	if notice != "" {
+		response.Warning(rsp, errors.New(notice)).TargetCompositeAndClaim()
+	}
+
+	access, err := accessFrom(params, containers)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid access parameter"))
+		return rsp, nil
+	}
+
+	queues, err := queuesFrom(params, sku)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid queues parameter"))
+		return rsp, nil
+	}
+
+	tables, err := tablesFrom(params, sku)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid tables parameter"))
+		return rsp, nil
+	}
+
+	shares, err := sharesFrom(params, sku)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid fileShares parameter"))
+		return rsp, nil
+	}
+
+	deletionPolicy, err := deletionPolicyFrom(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid deletionPolicy parameter"))
+		return rsp, nil
+	}
+
+	managementPolicies, err := managementPoliciesFrom(params)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid managementPolicies parameter"))
+		return rsp, nil
+	}
+
+	policies := resourcePolicies{DeletionPolicy: deletionPolicy, ManagementPolicies: managementPolicies}
+
+	// Publish details of the observed resources in the XR's status and
+	// connection details, so that consumers of the bucket don't need to look
+	// up the composed resources.
+	status, err := observedStatus(observedComposed, containers)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot derive xr status"))
+		return rsp, nil
+	}
+	cd := observedConnectionDetails(observedComposed, status)
+	if err := setDesiredComposite(req, rsp, status, cd); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot set desired xr"))
+		return rsp, nil
+	}
+
 	// We'll collect our desired composed resources into this map, then convert
 	// them to the SDK's types and set them in the response when we return.
 	desiredComposed := make(map[resource.Name]any)
@@ -66,6 +323,18 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 				response.Fatal(rsp, errors.Wrapf(err, "cannot convert %s to unstructured", name))
 				return
 			}
+			p := policies
+			if name == resourceGroupResourceName {
+				p = policies.ResourceGroup()
+			}
+			if err := p.Apply(c); err != nil {
+				response.Fatal(rsp, errors.Wrapf(err, "cannot set policies of %s", name))
+				return
+			}
+			if err := setProviderConfigRef(c, providerConfig); err != nil {
+				response.Fatal(rsp, errors.Wrapf(err, "cannot set ProviderConfig of %s", name))
+				return
+			}
 			desiredComposedResources[name] = &resource.DesiredComposed{Resource: c}
 		}
 
@@ -75,33 +344,72 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 		}
 	}()
 
-	// Determine container access type based on ACL
-	containerAccessType := "private"
-	if params.ACL != nil && *params.ACL == "public" {
-		containerAccessType = "blob"
+	// Storage account names are derived from the XR's name and UID. Once the
+	// account exists we keep using its name, so that changes to how names are
+	// derived never rename an existing account.
+	var accountName string
+	if oc, ok := observedComposed["account"]; ok {
+		accountName = oc.Resource.GetName()
 	}
-
-	// Storage account names must be 3-24 character, lowercase alphanumeric
-	// strings that are globally unique within Azure.
-	accountName := ""
-	if xr.Metadata != nil && xr.Metadata.Name != nil {
-		accountName = strings.ReplaceAll(*xr.Metadata.Name, "-", "")
+	if accountName == "" {
+		var name, uid string
+		if xr.Metadata != nil {
+			name, uid = ptr.Deref(xr.Metadata.Name, ""), ptr.Deref(xr.Metadata.UID, "")
+		}
+		accountName, err = deriveAccountName(name, uid)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "invalid storage account name"))
+			return rsp, nil
+		}
 	}
 
 	// Create ResourceGroup
-	rg := &azv1beta1.ResourceGroup{
-		APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
-		Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
-		Spec: &azv1beta1.ResourceGroupSpec{
-			ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-				Location: params.Location,
+	if group.Compose {
+		desiredComposed[resourceGroupResourceName] = &azv1beta1.ResourceGroup{
+			APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+			Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+			Spec: &azv1beta1.ResourceGroupSpec{
+				ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+					Location: params.Location,
+					Tags:     &tags,
+				},
 			},
-		},
+		}
+	}
+
+	// The storage account selects the composed resource group by controller
+	// reference, so we wait for the group to be ready before composing it.
+	// Once composed we keep desiring it, so that a temporarily unready group
+	// doesn't cause the account to be deleted.
+	if group.Name == "" && !isReady(observedComposed, resourceGroupResourceName) && !isObserved(observedComposed, "account") {
+		response.ConditionFalse(rsp, conditionTypeComposed, stageResourceGroup).
+			WithMessage("Waiting for the resource group to become ready before composing the storage account").
+			TargetCompositeAndClaim()
+		return rsp, nil
+	}
+
+	// Storage accounts encrypted with a customer-managed key access the key
+	// as a user-assigned identity. The account must be created with the
+	// identity assigned, so we wait for the identity to be ready too.
+	var identityID, principalID string
+	if cmk != nil {
+		desiredComposed[identityResourceName] = cmk.Identity(params.Location, group, tags)
+
+		if !isReady(observedComposed, identityResourceName) && !isObserved(observedComposed, "account") {
+			response.ConditionFalse(rsp, conditionTypeComposed, stageIdentity).
+				WithMessage("Waiting for the managed identity to become ready before composing the storage account").
+				TargetCompositeAndClaim()
+			return rsp, nil
+		}
+
+		identityID, principalID, err = observedIdentity(observedComposed)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot get managed identity"))
+			return rsp, nil
+		}
 	}
-	desiredComposed["rg"] = rg
 
 	// Create Storage Account
-	matchControllerRef := true
 	account := &storagev1beta1.Account{
 		APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
 		Kind:       ptr.To(storagev1beta1.AccountKindAccount),
@@ -110,41 +418,359 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest)
 		},
 		Spec: &storagev1beta1.AccountSpec{
 			ForProvider: &storagev1beta1.AccountSpecForProvider{
-				AccountTier:                     ptr.To("Standard"),
-				AccountReplicationType:          ptr.To("LRS"),
+				AccountTier:                     ptr.To(string(sku.Tier)),
+				AccountReplicationType:          ptr.To(string(sku.Replication)),
+				AccountKind:                     ptr.To(string(sku.Kind)),
 				Location:                        params.Location,
 				InfrastructureEncryptionEnabled: ptr.To(true),
-				BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{
-					{
-						VersioningEnabled: params.Versioning,
-					},
-				},
-				ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
-					MatchControllerRef: &matchControllerRef,
-				},
+				AllowNestedItemsToBePublic:      ptr.To(allowNestedItemsToBePublic(containers)),
+				BlobProperties:                  &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{bp},
+				PublicNetworkAccessEnabled:      network.PublicNetworkAccessEnabled,
+				NetworkRules:                    network.NetworkRules,
+				StaticWebsite:                   website,
+				Tags:                            &tags,
+			},
+			// The account's connection details are curated into the XR's
+			// connection details, so they must be written somewhere that
+			// exists.
+			WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+				Name:      ptr.To(accountName + "-account"),
+				Namespace: ptr.To(f.connectionSecretNamespace(observedComposite.Resource)),
 			},
 		},
 	}
+	if group.Name != "" {
+		account.Spec.ForProvider.ResourceGroupName = ptr.To(group.Name)
+	} else {
+		account.Spec.ForProvider.ResourceGroupNameSelector = &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+			MatchControllerRef: ptr.To(true),
+		}
+	}
+	if imm != nil {
+		account.Spec.ForProvider.ImmutabilityPolicy = imm.AccountPolicy()
+	}
+	if identityID != "" {
+		account.Spec.ForProvider.Identity = &[]storagev1beta1.AccountSpecForProviderIdentityItem{{
+			Type:        ptr.To("UserAssigned"),
+			IdentityIds: &[]string{identityID},
+		}}
+	}
 	desiredComposed["account"] = account
 
-	// Create Storage Container
-	container := &storagev1beta1.Container{
-		APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
-		Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
-		Spec: &storagev1beta1.ContainerSpec{
-			ForProvider: &storagev1beta1.ContainerSpecForProvider{
-				ContainerAccessType: ptr.To(containerAccessType),
-				StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
-					MatchControllerRef: &matchControllerRef,
-				},
-			},
-		},
+	// Grant the identity access to the customer-managed key
+	if principalID != "" {
+		desiredComposed[keyAccessResourceName] = cmk.RoleAssignment(principalID)
+	}
+
+	// Create the identity apps use to access the bucket
+	if access != nil && access.IdentityRole != "" {
+		desiredComposed[accessIdentityResourceName] = userAssignedIdentity(params.Location, group, tags)
+	}
+
+	// Federate the identity with a Kubernetes service account. The
+	// credential references the identity by ID, which is only known once the
+	// identity has been created.
+	if wi != nil {
+		ap, err := observedIdentityStatus(observedComposed, accessIdentityResourceName)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot get access identity"))
+			return rsp, nil
+		}
+		if ap != nil && ptr.Deref(ap.ID, "") != "" {
+			desiredComposed[workloadIdentityCredentialResourceName] = wi.FederatedIdentityCredential(*ap.ID, group)
+		}
+	}
+
+	// Likewise, the resources that depend on the storage account reference
+	// it, either by controller reference or by its ID.
+	accountDependents := []resource.Name{managementPolicyResourceName, privateEndpointResourceName, customerManagedKeyResourceName, accountDiagnosticsResourceName, blobDiagnosticsResourceName}
+	for _, c := range containers {
+		accountDependents = append(accountDependents, c.ResourceName)
+	}
+	for _, q := range queues {
+		accountDependents = append(accountDependents, q.ResourceName)
+	}
+	for _, t := range tables {
+		accountDependents = append(accountDependents, t.ResourceName)
+	}
+	for _, fs := range shares {
+		accountDependents = append(accountDependents, fs.ResourceName)
+	}
+	accountDependents = append(accountDependents, access.ResourceNames()...)
+	if !isReady(observedComposed, "account") && !isAnyObserved(observedComposed, accountDependents...) {
+		response.ConditionFalse(rsp, conditionTypeComposed, stageAccount).
+			WithMessage("Waiting for the storage account to become ready before composing the resources that depend on it").
+			TargetCompositeAndClaim()
+		return rsp, nil
+	}
+
+	// Create Storage Containers
+	for _, c := range containers {
+		desiredComposed[c.ResourceName] = c.Container()
+		if imm == nil {
+			continue
+		}
+
+		// The immutability policy references the container by ID, which
+		// is only known once the container has been created.
+		id, err := observedContainerID(observedComposed, c.ResourceName)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot get container ID"))
+			return rsp, nil
+		}
+		if id != "" {
+			desiredComposed[immutabilityPolicyResourceNamePrefix+c.ResourceName] = imm.ContainerPolicy(id)
+		}
+	}
+
+	// Create Storage Queues, Tables and File Shares
+	for _, q := range queues {
+		desiredComposed[q.ResourceName] = q.Queue()
+	}
+	for _, t := range tables {
+		desiredComposed[t.ResourceName] = t.Table()
+	}
+	for _, fs := range shares {
+		desiredComposed[fs.ResourceName] = fs.Share()
+	}
+
+	// Create Lifecycle Management Policy
+	if policy != nil {
+		desiredComposed[managementPolicyResourceName] = policy
+	}
+
+	// The private endpoint and diagnostic settings reference the account by
+	// ID, which is only known once the account has been created.
+	accountID, err := observedAccountID(observedComposed)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot get storage account ID"))
+		return rsp, nil
+	}
+
+	// Create Private Endpoint
+	if endpoint != nil && accountID != "" {
+		desiredComposed[privateEndpointResourceName] = endpoint.PrivateEndpoint(params.Location, accountID, group, tags)
+	}
+
+	// Create Diagnostic Settings
+	if diag != nil && accountID != "" {
+		for name, ds := range diag.DiagnosticSettings(accountID) {
+			desiredComposed[name] = ds
+		}
+	}
+
+	// Grant data-plane access
+	if access != nil {
+		ras, err := access.RoleAssignments(observedComposed)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot compose role assignments"))
+			return rsp, nil
+		}
+		for name, ra := range ras {
+			desiredComposed[name] = ra
+		}
 	}
-	desiredComposed["container"] = container
+
+	// Create Customer-Managed Key
+	// The account can only use the key once its identity has been granted
+	// access to it.
+	if cmk != nil {
+		if !isReady(observedComposed, keyAccessResourceName) && !isObserved(observedComposed, customerManagedKeyResourceName) {
+			response.ConditionFalse(rsp, conditionTypeComposed, stageKeyAccess).
+				WithMessage("Waiting for the managed identity to be granted access to the encryption key").
+				TargetCompositeAndClaim()
+			return rsp, nil
+		}
+		desiredComposed[customerManagedKeyResourceName] = cmk.AccountCustomerManagedKey(identityID)
+	}
+
+	response.ConditionTrue(rsp, conditionTypeComposed, stageComplete).
+		WithMessage("All resources have been composed").
+		TargetCompositeAndClaim()
 
 	return rsp, nil
 }
 
+// observedStatus returns the XR status derived from the observed composed
+// resources. Each field is only set once it has been observed.
+func observedStatus(observed map[resource.Name]resource.ObservedComposed, containers []bucketContainer) (*v1alpha1.XStorageBucketStatus, error) {
+	status := &v1alpha1.XStorageBucketStatus{}
+
+	if oc, ok := observed["account"]; ok {
+		account := &storagev1beta1.Account{}
+		if err := convertViaJSON(account, oc.Resource); err != nil {
+			return nil, errors.Wrap(err, "cannot convert observed account")
+		}
+		if name := meta.GetExternalName(oc.Resource); name != "" {
+			status.StorageAccountName = &name
+		}
+		if account.Status != nil && account.Status.AtProvider != nil {
+			status.ResourceGroupName = account.Status.AtProvider.ResourceGroupName
+			status.PrimaryBlobEndpoint = account.Status.AtProvider.PrimaryBlobEndpoint
+			status.PrimaryWebEndpoint = account.Status.AtProvider.PrimaryWebEndpoint
+		}
+	}
+
+	for _, c := range containers {
+		oc, ok := observed[c.ResourceName]
+		if !ok {
+			continue
+		}
+		name := meta.GetExternalName(oc.Resource)
+		if name == "" {
+			continue
+		}
+		item := v1alpha1.XStorageBucketStatusContainersItem{Name: ptr.To(name)}
+		if status.PrimaryBlobEndpoint != nil {
+			item.URL = ptr.To(containerURL(*status.PrimaryBlobEndpoint, name))
+		}
+		status.Containers = ptr.To(append(ptr.Deref(status.Containers, nil), item))
+
+		// The default container is also published on its own, for
+		// compatibility with XRs that don't list their containers.
+		if c.ResourceName == defaultContainerResourceName {
+			status.ContainerName, status.ContainerURL = item.Name, item.URL
+		}
+	}
+
+	if oc, ok := observed[privateEndpointResourceName]; ok {
+		pe := &networkv1beta1.PrivateEndpoint{}
+		if err := convertViaJSON(pe, oc.Resource); err != nil {
+			return nil, errors.Wrap(err, "cannot convert observed private endpoint")
+		}
+		if pe.Status != nil && pe.Status.AtProvider != nil {
+			for _, psc := range ptr.Deref(pe.Status.AtProvider.PrivateServiceConnection, nil) {
+				if ptr.Deref(psc.PrivateIPAddress, "") != "" {
+					status.PrivateIPAddress = psc.PrivateIPAddress
+					break
+				}
+			}
+		}
+	}
+
+	ap, err := observedIdentityStatus(observed, accessIdentityResourceName)
+	if err != nil {
+		return nil, err
+	}
+	if ap != nil {
+		status.IdentityClientID = ap.ClientID
+	}
+
+	return status, nil
+}
+
+// observedAccountID returns the Azure resource ID of the observed storage
+// account, or an empty string if it hasn't been observed yet.
+func observedAccountID(observed map[resource.Name]resource.ObservedComposed) (string, error) {
+	oc, ok := observed["account"]
+	if !ok {
+		return "", nil
+	}
+	account := &storagev1beta1.Account{}
+	if err := convertViaJSON(account, oc.Resource); err != nil {
+		return "", errors.Wrap(err, "cannot convert observed account")
+	}
+	if account.Status == nil || account.Status.AtProvider == nil {
+		return "", nil
+	}
+	return ptr.Deref(account.Status.AtProvider.ID, ""), nil
+}
+
+// observedConnectionDetails returns the XR connection details derived from the
+// supplied status and the storage account's observed connection details. Each
+// key is only set once it has been observed.
+func observedConnectionDetails(observed map[resource.Name]resource.ObservedComposed, status *v1alpha1.XStorageBucketStatus) resource.ConnectionDetails {
+	cd := make(resource.ConnectionDetails)
+
+	set := func(key string, value *string) {
+		if value != nil && *value != "" {
+			cd[key] = []byte(*value)
+		}
+	}
+	set(connectionKeyAccountName, status.StorageAccountName)
+	set(connectionKeyBlobEndpoint, status.PrimaryBlobEndpoint)
+	set(connectionKeyContainerName, status.ContainerName)
+
+	if oc, ok := observed["account"]; ok {
+		if v, ok := oc.ConnectionDetails[accountConnectionKeyPrimaryAccessKey]; ok {
+			cd[connectionKeyAccessKey] = v
+		}
+		if v, ok := oc.ConnectionDetails[accountConnectionKeyPrimaryConnectionString]; ok {
+			cd[connectionKeyConnectionString] = v
+		}
+	}
+
+	return cd
+}
+
+// connectionSecretNamespace returns the namespace composed resources write
+// their connection secrets to. It's the namespace the supplied XR writes its
+// own connection secret to, which Crossplane must be able to write to, or the
+// function's configured namespace if the XR doesn't write one.
+func (f *Function) connectionSecretNamespace(xr *composite.Unstructured) string {
+	if ref := xr.GetWriteConnectionSecretToReference(); ref != nil && ref.Namespace != "" {
+		return ref.Namespace
+	}
+	if f.secretNamespace != "" {
+		return f.secretNamespace
+	}
+	return defaultConnectionSecretNamespace
+}
+
+// setDesiredComposite merges the supplied status and connection details into
+// the desired XR, preserving any desired state accumulated by previous
+// functions in the pipeline. The desired XR is left untouched if there's
+// nothing to publish yet.
+func setDesiredComposite(req *fnv1.RunFunctionRequest, rsp *fnv1.RunFunctionResponse, status *v1alpha1.XStorageBucketStatus, cd resource.ConnectionDetails) error {
+	fields := make(map[string]any)
+	if err := convertViaJSON(&fields, status); err != nil {
+		return errors.Wrap(err, "cannot convert status")
+	}
+	if len(fields) == 0 && len(cd) == 0 {
+		return nil
+	}
+
+	dxr, err := request.GetDesiredCompositeResource(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot get desired xr")
+	}
+	for k, v := range fields {
+		if err := dxr.Resource.SetValue("status."+k, v); err != nil {
+			return errors.Wrapf(err, "cannot set status.%s", k)
+		}
+	}
+	for k, v := range cd {
+		dxr.ConnectionDetails[k] = v
+	}
+	return response.SetDesiredCompositeResource(rsp, dxr)
+}
+
+// isObserved returns true if the named composed resource exists.
+func isObserved(observed map[resource.Name]resource.ObservedComposed, name resource.Name) bool {
+	_, ok := observed[name]
+	return ok
+}
+
+// isAnyObserved returns true if any of the named composed resources exist.
+func isAnyObserved(observed map[resource.Name]resource.ObservedComposed, names ...resource.Name) bool {
+	for _, name := range names {
+		if isObserved(observed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isReady returns true if the named composed resource exists and its Ready
+// condition is true.
+func isReady(observed map[resource.Name]resource.ObservedComposed, name resource.Name) bool {
+	oc, ok := observed[name]
+	if !ok {
+		return false
+	}
+	return oc.Resource.GetCondition(xpv1.TypeReady).Status == corev1.ConditionTrue
+}
+
 func convertViaJSON(to, from any) error {
 	bs, err := json.Marshal(from)
 	if err != nil {
@@ -6,31 +6,44 @@ import (
 
 	"dev.upbound.io/models/com/example/platform/v1alpha1"
 	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	authorizationv1beta1 "dev.upbound.io/models/io/upbound/azure/authorization/v1beta1"
+	managedidentityv1beta1 "dev.upbound.io/models/io/upbound/azure/managedidentity/v1beta1"
+	networkv1beta1 "dev.upbound.io/models/io/upbound/azure/network/v1beta1"
 	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
 	azv1beta1 "dev.upbound.io/models/io/upbound/azure/v1beta1"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
 	"k8s.io/utils/ptr"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
 	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
 	"github.com/crossplane/function-sdk-go/resource/composite"
 	"github.com/crossplane/function-sdk-go/response"
 )
 
 func TestRunFunction(t *testing.T) {
 	type args struct {
-		ctx context.Context
-		req *fnv1.RunFunctionRequest
+		ctx          context.Context
+		req          *fnv1.RunFunctionRequest
+		requiredTags []string
 	}
 	type want struct {
 		rsp *fnv1.RunFunctionResponse
 		err error
 	}
 
+	environment, _ := structpb.NewStruct(map[string]any{
+		"apiextensions.crossplane.io/environment": map[string]any{
+			"providerConfigName": "subscription-b",
+		},
+	})
+
 	cases := map[string]struct {
 		reason string
 		args   args
@@ -48,18 +61,98 @@ func TestRunFunction(t *testing.T) {
 							},
 							Spec: &v1alpha1.XStorageBucketSpec{
 								Parameters: &v1alpha1.XStorageBucketSpecParameters{
-									Location:   ptr.To("us-east-1"),
-									ACL:        ptr.To("private"),
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForResourceGroup",
+							Message: ptr.To("Waiting for the resource group to become ready before composing the storage account"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"ResourceGroupReady": {
+			reason: "If the resource group is ready, the storage account should be desired too.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+								UID:  ptr.To("2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
 									Versioning: ptr.To(false),
 								},
 							},
 						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "super-group",
+									},
+								},
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+						},
 					},
 				},
 			},
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
 					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForAccount",
+							Message: ptr.To("Waiting for the storage account to become ready before composing the resources that depend on it"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
 					Desired: &fnv1.State{
 						Resources: map[string]*fnv1.Resource{
 							"rg": toResource(&azv1beta1.ResourceGroup{
@@ -67,7 +160,11 @@ func TestRunFunction(t *testing.T) {
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
@@ -75,32 +172,132 @@ func TestRunFunction(t *testing.T) {
 								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
 								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
 								Metadata: &metav1.ObjectMeta{
-									Name: ptr.To("examplexr"),
+									Name: ptr.To("examplexre848635a"),
 								},
 								Spec: &storagev1beta1.AccountSpec{
 									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
 										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
 											MatchControllerRef: ptr.To(true),
 										},
 										AccountTier:                     ptr.To("Standard"),
 										AccountReplicationType:          ptr.To("LRS"),
-										Location:                        ptr.To("us-east-1"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
 										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
 										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
 											VersioningEnabled: ptr.To(false),
 										}},
 									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexre848635a-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
 								},
 							}),
-							"container": toResource(&storagev1beta1.Container{
-								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
-								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
-								Spec: &storagev1beta1.ContainerSpec{
-									ForProvider: &storagev1beta1.ContainerSpecForProvider{
-										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+						},
+					},
+				},
+			},
+		},
+		"XRConnectionSecretNamespace": {
+			reason: "The storage account should write its connection secret to the namespace the XR writes its own to.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: withConnectionSecretNamespace(toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+								UID:  ptr.To("2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}), "team-a"),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "super-group",
+									},
+								},
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForAccount",
+							Message: ptr.To("Waiting for the storage account to become ready before composing the resources that depend on it"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexre848635a"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
 											MatchControllerRef: ptr.To(true),
 										},
-										ContainerAccessType: ptr.To("private"),
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexre848635a-account"),
+										Namespace: ptr.To("team-a"),
 									},
 								},
 							}),
@@ -109,8 +306,8 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-		"ResourceGroupCreated": {
-			reason: "If the resource group has been created, all resources should be desired.",
+		"ObserveOnly": {
+			reason: "If only observing is allowed, the resource group and storage account should be observed, and the resource group orphaned so that deleting it never deletes the account.",
 			args: args{
 				req: &fnv1.RunFunctionRequest{
 					Meta: &fnv1.RequestMeta{Tag: "hello"},
@@ -118,17 +315,21 @@ func TestRunFunction(t *testing.T) {
 						Composite: toResource(&v1alpha1.XStorageBucket{
 							Metadata: &metav1.ObjectMeta{
 								Name: ptr.To("example-xr"),
+								UID:  ptr.To("2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d"),
 							},
 							Spec: &v1alpha1.XStorageBucketSpec{
 								Parameters: &v1alpha1.XStorageBucketSpecParameters{
-									Location:   ptr.To("us-east-1"),
-									ACL:        ptr.To("private"),
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
 									Versioning: ptr.To(false),
+									ManagementPolicies: &[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem{
+										v1alpha1.XStorageBucketSpecParametersManagementPoliciesItemObserve,
+									},
 								},
 							},
 						}),
 						Resources: map[string]*fnv1.Resource{
-							"rg": toResource(&azv1beta1.ResourceGroup{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
 								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Metadata: &metav1.ObjectMeta{
@@ -138,7 +339,7 @@ func TestRunFunction(t *testing.T) {
 								},
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
@@ -148,7 +349,16 @@ func TestRunFunction(t *testing.T) {
 			},
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
-					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForAccount",
+							Message: ptr.To("Waiting for the storage account to become ready before composing the resources that depend on it"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 					Results: []*fnv1.Result{},
 					Desired: &fnv1.State{
 						Resources: map[string]*fnv1.Resource{
@@ -156,8 +366,14 @@ func TestRunFunction(t *testing.T) {
 								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Spec: &azv1beta1.ResourceGroupSpec{
+									DeletionPolicy:     ptr.To(azv1beta1.ResourceGroupSpecDeletionPolicyOrphan),
+									ManagementPolicies: &[]azv1beta1.ResourceGroupSpecManagementPoliciesItem{"Observe"},
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
@@ -165,32 +381,102 @@ func TestRunFunction(t *testing.T) {
 								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
 								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
 								Metadata: &metav1.ObjectMeta{
-									Name: ptr.To("examplexr"),
+									Name: ptr.To("examplexre848635a"),
 								},
 								Spec: &storagev1beta1.AccountSpec{
+									ManagementPolicies: &[]storagev1beta1.AccountSpecManagementPoliciesItem{"Observe"},
 									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
 										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
 											MatchControllerRef: ptr.To(true),
 										},
 										AccountTier:                     ptr.To("Standard"),
 										AccountReplicationType:          ptr.To("LRS"),
-										Location:                        ptr.To("us-east-1"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
 										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
 										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
 											VersioningEnabled: ptr.To(false),
 										}},
 									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexre848635a-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
 								},
 							}),
-							"container": toResource(&storagev1beta1.Container{
-								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
-								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
-								Spec: &storagev1beta1.ContainerSpec{
-									ForProvider: &storagev1beta1.ContainerSpecForProvider{
-										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
-											MatchControllerRef: ptr.To(true),
+						},
+					},
+				},
+			},
+		},
+		"ExistingResourceGroup": {
+			reason: "If an existing resource group is requested, the storage account should be desired in it straight away, without composing a resource group.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+								UID:  ptr.To("2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:          ptr.To("eastus"),
+									ACL:               ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning:        ptr.To(false),
+									ResourceGroupName: ptr.To("team-a-storage"),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForAccount",
+							Message: ptr.To("Waiting for the storage account to become ready before composing the resources that depend on it"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexre848635a"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
 										},
-										ContainerAccessType: ptr.To("private"),
+										ResourceGroupName:               ptr.To("team-a-storage"),
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexre848635a-account"),
+										Namespace: ptr.To("crossplane-system"),
 									},
 								},
 							}),
@@ -199,8 +485,8 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-		"ResourceGroupCreatedWithVersioning": {
-			reason: "If the resource group has been created with versioning requested, all resources should be desired.",
+		"AccountReadyWithVersioning": {
+			reason: "If the storage account is ready and versioning is requested, all resources should be desired.",
 			args: args{
 				req: &fnv1.RunFunctionRequest{
 					Meta: &fnv1.RequestMeta{Tag: "hello"},
@@ -211,14 +497,14 @@ func TestRunFunction(t *testing.T) {
 							},
 							Spec: &v1alpha1.XStorageBucketSpec{
 								Parameters: &v1alpha1.XStorageBucketSpecParameters{
-									Location:   ptr.To("us-east-1"),
-									ACL:        ptr.To("private"),
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
 									Versioning: ptr.To(true),
 								},
 							},
 						}),
 						Resources: map[string]*fnv1.Resource{
-							"rg": toResource(&azv1beta1.ResourceGroup{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
 								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Metadata: &metav1.ObjectMeta{
@@ -228,17 +514,33 @@ func TestRunFunction(t *testing.T) {
 								},
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+							}),
 						},
 					},
 				},
 			},
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
-					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 					Results: []*fnv1.Result{},
 					Desired: &fnv1.State{
 						Resources: map[string]*fnv1.Resource{
@@ -247,7 +549,11 @@ func TestRunFunction(t *testing.T) {
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
@@ -259,17 +565,27 @@ func TestRunFunction(t *testing.T) {
 								},
 								Spec: &storagev1beta1.AccountSpec{
 									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
 										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
 											MatchControllerRef: ptr.To(true),
 										},
 										AccountTier:                     ptr.To("Standard"),
 										AccountReplicationType:          ptr.To("LRS"),
-										Location:                        ptr.To("us-east-1"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
 										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
 										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
 											VersioningEnabled: ptr.To(true),
 										}},
 									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
 								},
 							}),
 							"container": toResource(&storagev1beta1.Container{
@@ -289,8 +605,8 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-		"ResourceGroupCreatedWithPublicACL": {
-			reason: "If the resource group has been created with public ACL requested, all resources should be desired.",
+		"AccountReadyWithPublicACL": {
+			reason: "If the storage account is ready and the deprecated public ACL is requested, all resources should be desired with blob access and a warning.",
 			args: args{
 				req: &fnv1.RunFunctionRequest{
 					Meta: &fnv1.RequestMeta{Tag: "hello"},
@@ -301,14 +617,14 @@ func TestRunFunction(t *testing.T) {
 							},
 							Spec: &v1alpha1.XStorageBucketSpec{
 								Parameters: &v1alpha1.XStorageBucketSpecParameters{
-									Location:   ptr.To("us-east-1"),
-									ACL:        ptr.To("public"),
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPublic),
 									Versioning: ptr.To(false),
 								},
 							},
 						}),
 						Resources: map[string]*fnv1.Resource{
-							"rg": toResource(&azv1beta1.ResourceGroup{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
 								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Metadata: &metav1.ObjectMeta{
@@ -318,18 +634,40 @@ func TestRunFunction(t *testing.T) {
 								},
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+							}),
 						},
 					},
 				},
 			},
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
-					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
-					Results: []*fnv1.Result{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `acl "public" is deprecated; use "blob" instead`,
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 					Desired: &fnv1.State{
 						Resources: map[string]*fnv1.Resource{
 							"rg": toResource(&azv1beta1.ResourceGroup{
@@ -337,7 +675,11 @@ func TestRunFunction(t *testing.T) {
 								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
 								Spec: &azv1beta1.ResourceGroupSpec{
 									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
-										Location: ptr.To("us-east-1"),
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
 									},
 								},
 							}),
@@ -349,17 +691,27 @@ func TestRunFunction(t *testing.T) {
 								},
 								Spec: &storagev1beta1.AccountSpec{
 									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
 										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
 											MatchControllerRef: ptr.To(true),
 										},
 										AccountTier:                     ptr.To("Standard"),
 										AccountReplicationType:          ptr.To("LRS"),
-										Location:                        ptr.To("us-east-1"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
 										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(true),
 										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
 											VersioningEnabled: ptr.To(false),
 										}},
 									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
 								},
 							}),
 							"container": toResource(&storagev1beta1.Container{
@@ -379,21 +731,1882 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			f := &Function{log: logging.NewNopLogger()}
-			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
-
-			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
-			}
-
-			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
-			}
-		})
+		"AccountComposedResourceGroupNotReady": {
+			reason: "If the storage account has already been composed, it should stay desired with its existing name even if the resource group is not ready.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+								UID:  ptr.To("2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForAccount",
+							Message: ptr.To("Waiting for the storage account to become ready before composing the resources that depend on it"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"AllResourcesObserved": {
+			reason: "If all resources have been observed, their details should be published in the XR status and connection details.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "super-group",
+									},
+								},
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": withConnectionDetails(toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "examplexr",
+									},
+								},
+								Status: &storagev1beta1.AccountStatus{
+									AtProvider: &storagev1beta1.AccountStatusAtProvider{
+										ResourceGroupName:   ptr.To("super-group"),
+										PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+									},
+								},
+							}), map[string][]byte{
+								"attribute.primary_access_key":        []byte("c2VjcmV0"),
+								"attribute.primary_connection_string": []byte("DefaultEndpointsProtocol=https;AccountName=examplexr;AccountKey=c2VjcmV0;EndpointSuffix=core.windows.net"),
+								"attribute.secondary_access_key":      []byte("c2Vjb25kYXJ5"),
+							}),
+							"container": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("example-xr-8s7fw"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "example-xr-8s7fw",
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
+					Desired: &fnv1.State{
+						Composite: withConnectionDetails(toResource(&v1alpha1.XStorageBucket{
+							Status: &v1alpha1.XStorageBucketStatus{
+								ResourceGroupName:   ptr.To("super-group"),
+								StorageAccountName:  ptr.To("examplexr"),
+								PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+								ContainerName:       ptr.To("example-xr-8s7fw"),
+								ContainerURL:        ptr.To("https://examplexr.blob.core.windows.net/example-xr-8s7fw"),
+								Containers: &[]v1alpha1.XStorageBucketStatusContainersItem{
+									{
+										Name: ptr.To("example-xr-8s7fw"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/example-xr-8s7fw"),
+									},
+								},
+							},
+						}), map[string][]byte{
+							"AZURE_STORAGE_ACCOUNT":           []byte("examplexr"),
+							"AZURE_STORAGE_KEY":               []byte("c2VjcmV0"),
+							"AZURE_STORAGE_BLOB_ENDPOINT":     []byte("https://examplexr.blob.core.windows.net/"),
+							"AZURE_STORAGE_CONTAINER":         []byte("example-xr-8s7fw"),
+							"AZURE_STORAGE_CONNECTION_STRING": []byte("DefaultEndpointsProtocol=https;AccountName=examplexr;AccountKey=c2VjcmV0;EndpointSuffix=core.windows.net"),
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"container": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"PrivateEndpointObserved": {
+			reason: "If a private endpoint is requested, it should be composed for the storage account, public network access should be disabled, and its private IP address should be published in the XR status.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+										SubnetID:         ptr.To("/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/hub/subnets/endpoints"),
+										PrivateDNSZoneID: ptr.To("/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/privateDnsZones/privatelink.blob.core.windows.net"),
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Status: &storagev1beta1.AccountStatus{
+									AtProvider: &storagev1beta1.AccountStatusAtProvider{
+										ID: ptr.To("/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.Storage/storageAccounts/examplexr"),
+									},
+								},
+							}),
+							"private-endpoint": toReadyResource(&networkv1beta1.PrivateEndpoint{
+								APIVersion: ptr.To(networkv1beta1.PrivateEndpointAPIVersionnetworkAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(networkv1beta1.PrivateEndpointKindPrivateEndpoint),
+								Status: &networkv1beta1.PrivateEndpointStatus{
+									AtProvider: &networkv1beta1.PrivateEndpointStatusAtProvider{
+										PrivateServiceConnection: &[]networkv1beta1.PrivateEndpointStatusAtProviderPrivateServiceConnectionItem{{
+											Name:             ptr.To("blob"),
+											PrivateIPAddress: ptr.To("10.0.1.4"),
+										}},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
+					Desired: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Status: &v1alpha1.XStorageBucketStatus{
+								PrivateIPAddress: ptr.To("10.0.1.4"),
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										PublicNetworkAccessEnabled:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"container": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"private-endpoint": toResource(&networkv1beta1.PrivateEndpoint{
+								APIVersion: ptr.To(networkv1beta1.PrivateEndpointAPIVersionnetworkAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(networkv1beta1.PrivateEndpointKindPrivateEndpoint),
+								Spec: &networkv1beta1.PrivateEndpointSpec{
+									ForProvider: &networkv1beta1.PrivateEndpointSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+										SubnetID: ptr.To("/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/hub/subnets/endpoints"),
+										PrivateServiceConnection: &[]networkv1beta1.PrivateEndpointSpecForProviderPrivateServiceConnectionItem{{
+											Name:                        ptr.To("blob"),
+											IsManualConnection:          ptr.To(false),
+											PrivateConnectionResourceID: ptr.To("/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.Storage/storageAccounts/examplexr"),
+											SubresourceNames:            &[]string{"blob"},
+										}},
+										PrivateDNSZoneGroup: &[]networkv1beta1.PrivateEndpointSpecForProviderPrivateDNSZoneGroupItem{{
+											Name:              ptr.To("default"),
+											PrivateDNSZoneIds: &[]string{"/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/privateDnsZones/privatelink.blob.core.windows.net"},
+										}},
+										ResourceGroupNameSelector: &networkv1beta1.PrivateEndpointSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"EncryptedAccountWaitingForKeyAccess": {
+			reason: "If a customer-managed key is requested, the storage account should be assigned the composed identity, and the key should only be composed once the identity has been granted access to it.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+										KeyVaultID: ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault"),
+										KeyID:      ptr.To("https://example-vault.vault.azure.net/keys/storage"),
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"identity": toReadyResource(&managedidentityv1beta1.UserAssignedIdentity{
+								APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+								Status: &managedidentityv1beta1.UserAssignedIdentityStatus{
+									AtProvider: &managedidentityv1beta1.UserAssignedIdentityStatusAtProvider{
+										ID:          ptr.To("/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example-xr-identity"),
+										PrincipalID: ptr.To("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+									},
+								},
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForKeyAccess",
+							Message: ptr.To("Waiting for the managed identity to be granted access to the encryption key"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"identity": toResource(&managedidentityv1beta1.UserAssignedIdentity{
+								APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+								Spec: &managedidentityv1beta1.UserAssignedIdentitySpec{
+									ForProvider: &managedidentityv1beta1.UserAssignedIdentitySpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+										ResourceGroupNameSelector: &managedidentityv1beta1.UserAssignedIdentitySpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										Identity: &[]storagev1beta1.AccountSpecForProviderIdentityItem{{
+											Type:        ptr.To("UserAssigned"),
+											IdentityIds: &[]string{"/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example-xr-identity"},
+										}},
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"key-access": toResource(&authorizationv1beta1.RoleAssignment{
+								APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+								Spec: &authorizationv1beta1.RoleAssignmentSpec{
+									ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+										PrincipalID:        ptr.To("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+										PrincipalType:      ptr.To("ServicePrincipal"),
+										RoleDefinitionName: ptr.To("Key Vault Crypto Service Encryption User"),
+										Scope:              ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault/keys/storage"),
+									},
+								},
+							}),
+							"container": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"ObserveOnlyEncryptedAccount": {
+			reason: "If only observing is allowed, every composed resource should only be observed, including the identity and role assignment that encrypt the storage account.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+										KeyVaultID: ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault"),
+										KeyID:      ptr.To("https://example-vault.vault.azure.net/keys/storage"),
+									},
+									ManagementPolicies: &[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem{
+										v1alpha1.XStorageBucketSpecParametersManagementPoliciesItemObserve,
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"identity": toReadyResource(&managedidentityv1beta1.UserAssignedIdentity{
+								APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+								Status: &managedidentityv1beta1.UserAssignedIdentityStatus{
+									AtProvider: &managedidentityv1beta1.UserAssignedIdentityStatusAtProvider{
+										ID:          ptr.To("/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example-xr-identity"),
+										PrincipalID: ptr.To("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+									},
+								},
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForKeyAccess",
+							Message: ptr.To("Waiting for the managed identity to be granted access to the encryption key"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									DeletionPolicy:     ptr.To(azv1beta1.ResourceGroupSpecDeletionPolicyOrphan),
+									ManagementPolicies: &[]azv1beta1.ResourceGroupSpecManagementPoliciesItem{"Observe"},
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"identity": toResource(&managedidentityv1beta1.UserAssignedIdentity{
+								APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+								Spec: &managedidentityv1beta1.UserAssignedIdentitySpec{
+									ManagementPolicies: &[]managedidentityv1beta1.UserAssignedIdentitySpecManagementPoliciesItem{"Observe"},
+									ForProvider: &managedidentityv1beta1.UserAssignedIdentitySpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+										ResourceGroupNameSelector: &managedidentityv1beta1.UserAssignedIdentitySpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ManagementPolicies: &[]storagev1beta1.AccountSpecManagementPoliciesItem{"Observe"},
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										Identity: &[]storagev1beta1.AccountSpecForProviderIdentityItem{{
+											Type:        ptr.To("UserAssigned"),
+											IdentityIds: &[]string{"/subscriptions/0000/resourceGroups/super-group/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example-xr-identity"},
+										}},
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"key-access": toResource(&authorizationv1beta1.RoleAssignment{
+								APIVersion: ptr.To(authorizationv1beta1.RoleAssignmentAPIVersionauthorizationAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(authorizationv1beta1.RoleAssignmentKindRoleAssignment),
+								Spec: &authorizationv1beta1.RoleAssignmentSpec{
+									ManagementPolicies: &[]authorizationv1beta1.RoleAssignmentSpecManagementPoliciesItem{"Observe"},
+									ForProvider: &authorizationv1beta1.RoleAssignmentSpecForProvider{
+										PrincipalID:        ptr.To("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+										PrincipalType:      ptr.To("ServicePrincipal"),
+										RoleDefinitionName: ptr.To("Key Vault Crypto Service Encryption User"),
+										Scope:              ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault/keys/storage"),
+									},
+								},
+							}),
+							"container": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Spec: &storagev1beta1.ContainerSpec{
+									ManagementPolicies: &[]storagev1beta1.ContainerSpecManagementPoliciesItem{"Observe"},
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"ContainersListed": {
+			reason: "If the XR lists its containers, a container should be desired for each of them.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+										{
+											Name: ptr.To("raw"),
+										},
+										{
+											Name:       ptr.To("exports"),
+											AccessType: ptr.To(v1alpha1.XStorageBucketSpecParametersContainersItemAccessTypeBlob),
+											Metadata: &map[string]string{
+												"team": "data",
+											},
+										},
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "examplexr",
+									},
+								},
+								Status: &storagev1beta1.AccountStatus{
+									AtProvider: &storagev1beta1.AccountStatusAtProvider{
+										PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+									},
+								},
+							}),
+							"container-raw": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("example-xr-2kx9q"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "raw",
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Composite: withConnectionDetails(toResource(&v1alpha1.XStorageBucket{
+							Status: &v1alpha1.XStorageBucketStatus{
+								StorageAccountName:  ptr.To("examplexr"),
+								PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+								Containers: &[]v1alpha1.XStorageBucketStatusContainersItem{
+									{
+										Name: ptr.To("raw"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/raw"),
+									},
+								},
+							},
+						}), map[string][]byte{
+							"AZURE_STORAGE_ACCOUNT":       []byte("examplexr"),
+							"AZURE_STORAGE_BLOB_ENDPOINT": []byte("https://examplexr.blob.core.windows.net/"),
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(true),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"container-raw": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "raw",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"container-exports": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "exports",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("blob"),
+										Metadata: &map[string]string{
+											"team": "data",
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"ImmutableContainersNamedLikePolicies": {
+			reason: "Each container's immutability policy should be desired under its own name, even if another container is named like the policy.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(true),
+									Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+										RetentionDays: ptr.To(365),
+									},
+									Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+										{
+											Name: ptr.To("logs"),
+										},
+										{
+											Name: ptr.To("logs-immutability"),
+										},
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "examplexr",
+									},
+								},
+								Status: &storagev1beta1.AccountStatus{
+									AtProvider: &storagev1beta1.AccountStatusAtProvider{
+										PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+									},
+								},
+							}),
+							"container-logs": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "logs",
+									},
+								},
+								Status: &storagev1beta1.ContainerStatus{
+									AtProvider: &storagev1beta1.ContainerStatusAtProvider{
+										ResourceManagerID: ptr.To("/subscriptions/0000/resourceGroups/example/providers/Microsoft.Storage/storageAccounts/examplexr/blobServices/default/containers/logs"),
+									},
+								},
+							}),
+							"container-logs-immutability": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "logs-immutability",
+									},
+								},
+								Status: &storagev1beta1.ContainerStatus{
+									AtProvider: &storagev1beta1.ContainerStatusAtProvider{
+										ResourceManagerID: ptr.To("/subscriptions/0000/resourceGroups/example/providers/Microsoft.Storage/storageAccounts/examplexr/blobServices/default/containers/logs-immutability"),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Composite: withConnectionDetails(toResource(&v1alpha1.XStorageBucket{
+							Status: &v1alpha1.XStorageBucketStatus{
+								StorageAccountName:  ptr.To("examplexr"),
+								PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+								Containers: &[]v1alpha1.XStorageBucketStatusContainersItem{
+									{
+										Name: ptr.To("logs"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/logs"),
+									},
+									{
+										Name: ptr.To("logs-immutability"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/logs-immutability"),
+									},
+								},
+							},
+						}), map[string][]byte{
+							"AZURE_STORAGE_ACCOUNT":       []byte("examplexr"),
+							"AZURE_STORAGE_BLOB_ENDPOINT": []byte("https://examplexr.blob.core.windows.net/"),
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(false),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(true),
+										}},
+										ImmutabilityPolicy: &[]storagev1beta1.AccountSpecForProviderImmutabilityPolicyItem{{
+											AllowProtectedAppendWrites: ptr.To(false),
+											PeriodSinceCreationInDays:  ptr.To(365.0),
+											State:                      ptr.To("Unlocked"),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"container-logs": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "logs",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"immutability-container-logs": toResource(&storagev1beta1.ContainerImmutabilityPolicy{
+								APIVersion: ptr.To(storagev1beta1.ContainerImmutabilityPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerImmutabilityPolicyKindContainerImmutabilityPolicy),
+								Spec: &storagev1beta1.ContainerImmutabilityPolicySpec{
+									ForProvider: &storagev1beta1.ContainerImmutabilityPolicySpecForProvider{
+										StorageContainerResourceManagerID: ptr.To("/subscriptions/0000/resourceGroups/example/providers/Microsoft.Storage/storageAccounts/examplexr/blobServices/default/containers/logs"),
+										ImmutabilityPeriodInDays:          ptr.To(365.0),
+										Locked:                            ptr.To(false),
+										ProtectedAppendWritesEnabled:      ptr.To(false),
+									},
+								},
+							}),
+							"container-logs-immutability": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "logs-immutability",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"immutability-container-logs-immutability": toResource(&storagev1beta1.ContainerImmutabilityPolicy{
+								APIVersion: ptr.To(storagev1beta1.ContainerImmutabilityPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerImmutabilityPolicyKindContainerImmutabilityPolicy),
+								Spec: &storagev1beta1.ContainerImmutabilityPolicySpec{
+									ForProvider: &storagev1beta1.ContainerImmutabilityPolicySpecForProvider{
+										StorageContainerResourceManagerID: ptr.To("/subscriptions/0000/resourceGroups/example/providers/Microsoft.Storage/storageAccounts/examplexr/blobServices/default/containers/logs-immutability"),
+										ImmutabilityPeriodInDays:          ptr.To(365.0),
+										Locked:                            ptr.To(false),
+										ProtectedAppendWritesEnabled:      ptr.To(false),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"ContainersListedDefaultObserved": {
+			reason: "If an XR whose default container exists starts listing its containers, the default container should still be desired so that its blobs aren't deleted.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Containers: &[]v1alpha1.XStorageBucketSpecParametersContainersItem{
+										{
+											Name: ptr.To("raw"),
+										},
+										{
+											Name:       ptr.To("exports"),
+											AccessType: ptr.To(v1alpha1.XStorageBucketSpecParametersContainersItemAccessTypeBlob),
+											Metadata: &map[string]string{
+												"team": "data",
+											},
+										},
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toReadyResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+							}),
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "examplexr",
+									},
+								},
+								Status: &storagev1beta1.AccountStatus{
+									AtProvider: &storagev1beta1.AccountStatusAtProvider{
+										PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+									},
+								},
+							}),
+							"container": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("example-xr-7jxhk"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "example-xr-7jxhk",
+									},
+								},
+							}),
+							"container-raw": toReadyResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("example-xr-2kx9q"),
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "raw",
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "AllResourcesComposed",
+							Message: ptr.To("All resources have been composed"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `Keeping the default container "example-xr-7jxhk": listing containers would delete it and its blobs`,
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Composite: withConnectionDetails(toResource(&v1alpha1.XStorageBucket{
+							Status: &v1alpha1.XStorageBucketStatus{
+								StorageAccountName:  ptr.To("examplexr"),
+								PrimaryBlobEndpoint: ptr.To("https://examplexr.blob.core.windows.net/"),
+								ContainerName:       ptr.To("example-xr-7jxhk"),
+								ContainerURL:        ptr.To("https://examplexr.blob.core.windows.net/example-xr-7jxhk"),
+								Containers: &[]v1alpha1.XStorageBucketStatusContainersItem{
+									{
+										Name: ptr.To("example-xr-7jxhk"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/example-xr-7jxhk"),
+									},
+									{
+										Name: ptr.To("raw"),
+										URL:  ptr.To("https://examplexr.blob.core.windows.net/raw"),
+									},
+								},
+							},
+						}), map[string][]byte{
+							"AZURE_STORAGE_ACCOUNT":       []byte("examplexr"),
+							"AZURE_STORAGE_BLOB_ENDPOINT": []byte("https://examplexr.blob.core.windows.net/"),
+							"AZURE_STORAGE_CONTAINER":     []byte("example-xr-7jxhk"),
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+							"account": toResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("examplexr"),
+								},
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										ResourceGroupNameSelector: &storagev1beta1.AccountSpecForProviderResourceGroupNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										AccountTier:                     ptr.To("Standard"),
+										AccountReplicationType:          ptr.To("LRS"),
+										AccountKind:                     ptr.To("StorageV2"),
+										Location:                        ptr.To("eastus"),
+										InfrastructureEncryptionEnabled: ptr.To(true),
+										AllowNestedItemsToBePublic:      ptr.To(true),
+										BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
+											VersioningEnabled: ptr.To(false),
+										}},
+									},
+									WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+										Name:      ptr.To("examplexr-account"),
+										Namespace: ptr.To("crossplane-system"),
+									},
+								},
+							}),
+							"container": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"container-raw": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "raw",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("private"),
+									},
+								},
+							}),
+							"container-exports": toResource(&storagev1beta1.Container{
+								APIVersion: ptr.To(storagev1beta1.ContainerAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
+								Metadata: &metav1.ObjectMeta{
+									Annotations: &map[string]string{
+										"crossplane.io/external-name": "exports",
+									},
+								},
+								Spec: &storagev1beta1.ContainerSpec{
+									ForProvider: &storagev1beta1.ContainerSpecForProvider{
+										StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
+											MatchControllerRef: ptr.To(true),
+										},
+										ContainerAccessType: ptr.To("blob"),
+										Metadata: &map[string]string{
+											"team": "data",
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		"InvalidAccountName": {
+			reason: "If no valid storage account name can be derived, the function should return a fatal result.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("x-r"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid storage account name: cannot derive a storage account name from "x-r": must contain at least 3 alphanumeric characters`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Desired: &fnv1.State{},
+				},
+			},
+		},
+		"UnsupportedSKU": {
+			reason: "If the requested tier, replication and kind aren't supported by Azure, the function should return a fatal result.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:    ptr.To("eastus"),
+									ACL:         ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning:  ptr.To(false),
+									Tier:        ptr.To(v1alpha1.XStorageBucketSpecParametersTierPremium),
+									Replication: ptr.To(v1alpha1.XStorageBucketSpecParametersReplicationGRS),
+									Kind:        ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "unsupported storage account configuration: Premium BlockBlobStorage storage accounts don't support GRS replication; use one of LRS, ZRS",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ProviderConfigRequired": {
+			reason: "If the XR names a ProviderConfig, the function should require it and compose nothing until Crossplane supplies it.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:           ptr.To("eastus"),
+									ACL:                ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning:         ptr.To(false),
+									ProviderConfigName: ptr.To("subscription-a"),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Requirements: &fnv1.Requirements{
+						ExtraResources: map[string]*fnv1.ResourceSelector{
+							"provider-config": {
+								ApiVersion: "azure.upbound.io/v1beta1",
+								Kind:       "ProviderConfig",
+								Match:      &fnv1.ResourceSelector_MatchName{MatchName: "subscription-a"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ProviderConfigMissing": {
+			reason: "If the named ProviderConfig doesn't exist, the function should return a fatal result.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:           ptr.To("eastus"),
+									ACL:                ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning:         ptr.To(false),
+									ProviderConfigName: ptr.To("subscription-a"),
+								},
+							},
+						}),
+					},
+					ExtraResources: map[string]*fnv1.Resources{
+						"provider-config": {},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `ProviderConfig "subscription-a" doesn't exist`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Requirements: &fnv1.Requirements{
+						ExtraResources: map[string]*fnv1.ResourceSelector{
+							"provider-config": {
+								ApiVersion: "azure.upbound.io/v1beta1",
+								Kind:       "ProviderConfig",
+								Match:      &fnv1.ResourceSelector_MatchName{MatchName: "subscription-a"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ProviderConfigFromEnvironment": {
+			reason: "If the XR doesn't name a ProviderConfig, the environment's ProviderConfig should be set on every composed resource.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta:    &fnv1.RequestMeta{Tag: "hello"},
+					Context: environment,
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+					},
+					ExtraResources: map[string]*fnv1.Resources{
+						"provider-config": {
+							Items: []*fnv1.Resource{toResource(&azv1beta1.ProviderConfig{
+								APIVersion: ptr.To(azv1beta1.ProviderConfigAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ProviderConfigKindProviderConfig),
+								Metadata: &metav1.ObjectMeta{
+									Name: ptr.To("subscription-b"),
+								},
+							})},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Context: environment,
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "Composed",
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  "WaitingForResourceGroup",
+							Message: ptr.To("Waiting for the resource group to become ready before composing the storage account"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"rg": toResource(&azv1beta1.ResourceGroup{
+								APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+								Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+								Spec: &azv1beta1.ResourceGroupSpec{
+									ProviderConfigRef: &azv1beta1.ResourceGroupSpecProviderConfigRef{
+										Name: ptr.To("subscription-b"),
+									},
+									ForProvider: &azv1beta1.ResourceGroupSpecForProvider{
+										Tags: &map[string]string{
+											"crossplane-composite": "example-xr",
+											"managed-by":           "crossplane",
+										},
+										Location: ptr.To("eastus"),
+									},
+								},
+							}),
+						},
+					},
+					Requirements: &fnv1.Requirements{
+						ExtraResources: map[string]*fnv1.ResourceSelector{
+							"provider-config": {
+								ApiVersion: "azure.upbound.io/v1beta1",
+								Kind:       "ProviderConfig",
+								Match:      &fnv1.ResourceSelector_MatchName{MatchName: "subscription-b"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"UnknownLocation": {
+			reason: "If a region Azure doesn't have is requested, the function should return a fatal result rather than leave the provider to reject it.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("East US 3"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid location parameter: unknown AzurePublicCloud region "East US 3"; did you mean "eastus"?`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"UnsupportedACL": {
+			reason: "If an ACL the XRD doesn't allow is requested, the function should return a fatal result rather than default to private.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACL("Public")),
+									Versioning: ptr.To(false),
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid acl parameter: unsupported ACL "Public"; use one of private, blob, container`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"InvalidNetwork": {
+			reason: "If the requested network rules are invalid, the function should return a fatal result.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+										AllowedIPRanges: &[]string{""},
+									},
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid network parameter: invalid allowed IP range "": must not be blank`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"InvalidEncryption": {
+			reason: "If the requested customer-managed key is malformed, the function should return a fatal result rather than compose the storage account.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Encryption: &v1alpha1.XStorageBucketSpecParametersEncryption{
+										KeyVaultID: ptr.To("/subscriptions/0000/resourceGroups/security/providers/Microsoft.KeyVault/vaults/example-vault"),
+										KeyID:      ptr.To("example-vault/storage"),
+									},
+								},
+							},
+						}),
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid encryption parameter: invalid key ID "example-vault/storage": must be an https URL`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"MissingRequiredTags": {
+			reason: "If the XR doesn't set the tags the platform requires, the function should return a fatal result rather than compose anything.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(false),
+									Tags: &map[string]string{
+										"cost-center": "cc-1234",
+									},
+								},
+							},
+						}),
+					},
+				},
+				requiredTags: []string{"owner", "cost-center"},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  `invalid tags parameter: missing required tag "owner"`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"LockedImmutabilityLoosened": {
+			reason: "If the XR loosens a locked immutability policy, the function should refuse with a fatal result rather than compose anything.",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Observed: &fnv1.State{
+						Composite: toResource(&v1alpha1.XStorageBucket{
+							Metadata: &metav1.ObjectMeta{
+								Name: ptr.To("example-xr"),
+							},
+							Spec: &v1alpha1.XStorageBucketSpec{
+								Parameters: &v1alpha1.XStorageBucketSpecParameters{
+									Location:   ptr.To("eastus"),
+									ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+									Versioning: ptr.To(true),
+									Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+										RetentionDays: ptr.To(365),
+										State:         ptr.To(v1alpha1.XStorageBucketSpecParametersImmutabilityStateUnlocked),
+									},
+								},
+							},
+						}),
+						Resources: map[string]*fnv1.Resource{
+							"account": toReadyResource(&storagev1beta1.Account{
+								APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+								Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+								Spec: &storagev1beta1.AccountSpec{
+									ForProvider: &storagev1beta1.AccountSpecForProvider{
+										ImmutabilityPolicy: &[]storagev1beta1.AccountSpecForProviderImmutabilityPolicyItem{{
+											PeriodSinceCreationInDays: ptr.To(365.0),
+											State:                     ptr.To("Locked"),
+										}},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "refusing to loosen immutability policy: locked policies can't be unlocked",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger(), requiredTags: tc.args.requiredTags}
+			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+
+			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// withConnectionDetails sets the supplied connection details on a resource.
+func withConnectionDetails(r *fnv1.Resource, cd map[string][]byte) *fnv1.Resource {
+	r.ConnectionDetails = cd
+	return r
+}
+
+// withConnectionSecretNamespace sets the namespace a composite resource writes
+// its connection secret to.
+func withConnectionSecretNamespace(r *fnv1.Resource, namespace string) *fnv1.Resource {
+	xr := composite.New()
+	_ = resource.AsObject(r.GetResource(), xr)
+	xr.SetWriteConnectionSecretToReference(&xpv1.SecretReference{Name: "example-xr", Namespace: namespace})
+	r.Resource, _ = resource.AsStruct(xr)
+	return r
+}
+
+// toReadyResource is like toResource, but marks the resource as ready.
+func toReadyResource(in any) *fnv1.Resource {
+	obj := composed.New()
+	_ = convertViaJSON(obj, in)
+	obj.SetConditions(xpv1.Available())
+	pb, _ := resource.AsStruct(obj)
+	return &fnv1.Resource{
+		Resource: pb,
 	}
 }
 
@@ -0,0 +1,49 @@
+package main
+
+import (
+	managedidentityv1beta1 "dev.upbound.io/models/io/upbound/azure/managedidentity/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// userAssignedIdentity returns a composed user-assigned identity in the
+// supplied location and resource group.
+func userAssignedIdentity(location *string, group bucketResourceGroup, tags map[string]string) *managedidentityv1beta1.UserAssignedIdentity {
+	id := &managedidentityv1beta1.UserAssignedIdentity{
+		APIVersion: ptr.To(managedidentityv1beta1.UserAssignedIdentityAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(managedidentityv1beta1.UserAssignedIdentityKindUserAssignedIdentity),
+		Spec: &managedidentityv1beta1.UserAssignedIdentitySpec{
+			ForProvider: &managedidentityv1beta1.UserAssignedIdentitySpecForProvider{
+				Location: location,
+				Tags:     &tags,
+			},
+		},
+	}
+	if group.Name != "" {
+		id.Spec.ForProvider.ResourceGroupName = ptr.To(group.Name)
+	} else {
+		id.Spec.ForProvider.ResourceGroupNameSelector = &managedidentityv1beta1.UserAssignedIdentitySpecForProviderResourceGroupNameSelector{
+			MatchControllerRef: ptr.To(true),
+		}
+	}
+	return id
+}
+
+// observedIdentityStatus returns the observed state of the named user-assigned
+// identity, or nil if it hasn't been observed yet.
+func observedIdentityStatus(observed map[resource.Name]resource.ObservedComposed, name resource.Name) (*managedidentityv1beta1.UserAssignedIdentityStatusAtProvider, error) {
+	oc, ok := observed[name]
+	if !ok {
+		return nil, nil
+	}
+	identity := &managedidentityv1beta1.UserAssignedIdentity{}
+	if err := convertViaJSON(identity, oc.Resource); err != nil {
+		return nil, errors.Wrapf(err, "cannot convert observed %s", name)
+	}
+	if identity.Status == nil {
+		return nil, nil
+	}
+	return identity.Status.AtProvider, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// immutabilityPolicyResourceNamePrefix prefixes the name of a container's
+// composed resource to name the composed resource for its immutability policy.
+// It's a prefix rather than a suffix so that policies can't be named like the
+// containers an XR lists, whose names all start with "container".
+const immutabilityPolicyResourceNamePrefix = "immutability-"
+
+// An immutability policy keeps blobs from being modified or deleted until
+// they're older than its retention period.
+type immutability struct {
+	// RetentionDays is the number of days after creation that blobs can't be
+	// modified or deleted for.
+	RetentionDays int
+
+	// Locked policies can't be removed or unlocked, and their retention can
+	// only be extended.
+	Locked bool
+
+	// Notice explains why a requested legal hold isn't set, if one is
+	// requested.
+	Notice string
+}
+
+// immutabilityFrom returns the immutability policy requested by the supplied
+// parameters, or nil if no immutability policy is requested.
+func immutabilityFrom(params *v1alpha1.XStorageBucketSpecParameters) (*immutability, error) {
+	if params.Immutability == nil {
+		return nil, nil
+	}
+
+	// Immutability policies apply to each version of a blob.
+	if !ptr.Deref(params.Versioning, false) {
+		return nil, errors.New("immutability requires versioning")
+	}
+
+	days := ptr.Deref(params.Immutability.RetentionDays, 0)
+	if days < 1 {
+		return nil, errors.New("retentionDays must be at least 1")
+	}
+
+	imm := &immutability{
+		RetentionDays: days,
+		Locked:        ptr.Deref(params.Immutability.State, v1alpha1.XStorageBucketSpecParametersImmutabilityStateUnlocked) == v1alpha1.XStorageBucketSpecParametersImmutabilityStateLocked,
+	}
+
+	// The provider can't set legal holds, so we can only explain how to set
+	// them instead.
+	if ptr.Deref(params.Immutability.LegalHold, false) {
+		imm.Notice = `legal holds aren't supported; set them on the bucket's containers with "az storage container legal-hold set"`
+	}
+	return imm, nil
+}
+
+// observedLockedImmutability returns the strictest locked immutability policy
+// of the observed storage account and containers, or nil if none are locked.
+func observedLockedImmutability(observed map[resource.Name]resource.ObservedComposed) (*immutability, error) {
+	var locked *immutability
+	lock := func(days float64) {
+		if locked == nil || int(days) > locked.RetentionDays {
+			locked = &immutability{RetentionDays: int(days), Locked: true}
+		}
+	}
+
+	if oc, ok := observed["account"]; ok {
+		account := &storagev1beta1.Account{}
+		if err := convertViaJSON(account, oc.Resource); err != nil {
+			return nil, errors.Wrap(err, "cannot convert observed account")
+		}
+		if account.Spec != nil && account.Spec.ForProvider != nil {
+			for _, p := range ptr.Deref(account.Spec.ForProvider.ImmutabilityPolicy, nil) {
+				if ptr.Deref(p.State, "") == string(v1alpha1.XStorageBucketSpecParametersImmutabilityStateLocked) {
+					lock(ptr.Deref(p.PeriodSinceCreationInDays, 0))
+				}
+			}
+		}
+	}
+
+	// Containers may have been removed from the XR since their policies were
+	// locked, so we consider every observed container policy.
+	for name, oc := range observed {
+		if oc.Resource.GetKind() != string(storagev1beta1.ContainerImmutabilityPolicyKindContainerImmutabilityPolicy) {
+			continue
+		}
+		p := &storagev1beta1.ContainerImmutabilityPolicy{}
+		if err := convertViaJSON(p, oc.Resource); err != nil {
+			return nil, errors.Wrapf(err, "cannot convert observed %s", name)
+		}
+		if p.Spec != nil && p.Spec.ForProvider != nil && ptr.Deref(p.Spec.ForProvider.Locked, false) {
+			lock(ptr.Deref(p.Spec.ForProvider.ImmutabilityPeriodInDays, 0))
+		}
+	}
+
+	return locked, nil
+}
+
+// validateImmutabilityChange returns an error if the requested immutability
+// policy would loosen the supplied locked policy. Azure refuses to loosen
+// locked policies, so that WORM storage stays compliant.
+func validateImmutabilityChange(requested, locked *immutability) error {
+	switch {
+	case locked == nil:
+		return nil
+	case requested == nil:
+		return errors.New("locked policies can't be removed")
+	case !requested.Locked:
+		return errors.New("locked policies can't be unlocked")
+	case requested.RetentionDays < locked.RetentionDays:
+		return errors.Errorf("the retention of locked policies can't be reduced from %d to %d days", locked.RetentionDays, requested.RetentionDays)
+	}
+	return nil
+}
+
+// AccountPolicy returns the storage account's default immutability policy,
+// which enables version-level immutability for the account.
+func (i immutability) AccountPolicy() *[]storagev1beta1.AccountSpecForProviderImmutabilityPolicyItem {
+	state := v1alpha1.XStorageBucketSpecParametersImmutabilityStateUnlocked
+	if i.Locked {
+		state = v1alpha1.XStorageBucketSpecParametersImmutabilityStateLocked
+	}
+	return &[]storagev1beta1.AccountSpecForProviderImmutabilityPolicyItem{{
+		AllowProtectedAppendWrites: ptr.To(false),
+		PeriodSinceCreationInDays:  days(&i.RetentionDays),
+		State:                      ptr.To(string(state)),
+	}}
+}
+
+// ContainerPolicy returns the composed immutability policy for the container
+// with the supplied resource ID.
+func (i immutability) ContainerPolicy(containerID string) *storagev1beta1.ContainerImmutabilityPolicy {
+	return &storagev1beta1.ContainerImmutabilityPolicy{
+		APIVersion: ptr.To(storagev1beta1.ContainerImmutabilityPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.ContainerImmutabilityPolicyKindContainerImmutabilityPolicy),
+		Spec: &storagev1beta1.ContainerImmutabilityPolicySpec{
+			ForProvider: &storagev1beta1.ContainerImmutabilityPolicySpecForProvider{
+				StorageContainerResourceManagerID: ptr.To(containerID),
+				ImmutabilityPeriodInDays:          days(&i.RetentionDays),
+				Locked:                            ptr.To(i.Locked),
+				ProtectedAppendWritesEnabled:      ptr.To(false),
+			},
+		},
+	}
+}
+
+// observedContainerID returns the Azure resource ID of the named observed
+// container, or an empty string if it hasn't been observed yet.
+func observedContainerID(observed map[resource.Name]resource.ObservedComposed, name resource.Name) (string, error) {
+	oc, ok := observed[name]
+	if !ok {
+		return "", nil
+	}
+	c := &storagev1beta1.Container{}
+	if err := convertViaJSON(c, oc.Resource); err != nil {
+		return "", errors.Wrapf(err, "cannot convert observed %s", name)
+	}
+	if c.Status == nil || c.Status.AtProvider == nil {
+		return "", nil
+	}
+	return ptr.Deref(c.Status.AtProvider.ResourceManagerID, ""), nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestImmutabilityFrom(t *testing.T) {
+	type want struct {
+		imm *immutability
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no immutability is requested, no immutability policy should be returned.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			want:   want{},
+		},
+		"Unlocked": {
+			reason: "Immutability policies should be unlocked unless the XR locks them.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+					RetentionDays: ptr.To(365),
+				},
+			},
+			want: want{
+				imm: &immutability{RetentionDays: 365},
+			},
+		},
+		"Locked": {
+			reason: "Immutability policies should be locked if the XR locks them.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+					RetentionDays: ptr.To(365),
+					State:         ptr.To(v1alpha1.XStorageBucketSpecParametersImmutabilityStateLocked),
+				},
+			},
+			want: want{
+				imm: &immutability{RetentionDays: 365, Locked: true},
+			},
+		},
+		"LegalHold": {
+			reason: "Requested legal holds should be explained rather than silently ignored, because they can't be set.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(true),
+				Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+					RetentionDays: ptr.To(365),
+					LegalHold:     ptr.To(true),
+				},
+			},
+			want: want{
+				imm: &immutability{
+					RetentionDays: 365,
+					Notice:        `legal holds aren't supported; set them on the bucket's containers with "az storage container legal-hold set"`,
+				},
+			},
+		},
+		"WithoutVersioning": {
+			reason: "Immutability should be rejected unless versioning is enabled.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning: ptr.To(false),
+				Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{
+					RetentionDays: ptr.To(365),
+				},
+			},
+			want: want{
+				err: errors.New("immutability requires versioning"),
+			},
+		},
+		"WithoutRetention": {
+			reason: "Immutability should be rejected unless a retention period is set.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Versioning:   ptr.To(true),
+				Immutability: &v1alpha1.XStorageBucketSpecParametersImmutability{},
+			},
+			want: want{
+				err: errors.New("retentionDays must be at least 1"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := immutabilityFrom(tc.params)
+
+			if diff := cmp.Diff(tc.want.imm, got); diff != "" {
+				t.Errorf("%s\nimmutabilityFrom(...): -want immutability, +got immutability:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nimmutabilityFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObservedLockedImmutability(t *testing.T) {
+	observedPolicy := func(p *storagev1beta1.ContainerImmutabilityPolicy) resource.ObservedComposed {
+		c := composed.New()
+		_ = convertViaJSON(c, p)
+		return resource.ObservedComposed{Resource: c}
+	}
+	policy := func(days float64, locked bool) *storagev1beta1.ContainerImmutabilityPolicy {
+		return &storagev1beta1.ContainerImmutabilityPolicy{
+			APIVersion: ptr.To(storagev1beta1.ContainerImmutabilityPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+			Kind:       ptr.To(storagev1beta1.ContainerImmutabilityPolicyKindContainerImmutabilityPolicy),
+			Spec: &storagev1beta1.ContainerImmutabilityPolicySpec{
+				ForProvider: &storagev1beta1.ContainerImmutabilityPolicySpecForProvider{
+					ImmutabilityPeriodInDays: ptr.To(days),
+					Locked:                   ptr.To(locked),
+				},
+			},
+		}
+	}
+
+	account := composed.New()
+	_ = convertViaJSON(account, &storagev1beta1.Account{
+		APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+		Spec: &storagev1beta1.AccountSpec{
+			ForProvider: &storagev1beta1.AccountSpecForProvider{
+				ImmutabilityPolicy: &[]storagev1beta1.AccountSpecForProviderImmutabilityPolicyItem{{
+					PeriodSinceCreationInDays: ptr.To(30.0),
+					State:                     ptr.To("Locked"),
+				}},
+			},
+		},
+	})
+
+	cases := map[string]struct {
+		reason   string
+		observed map[resource.Name]resource.ObservedComposed
+		want     *immutability
+	}{
+		"NoneLocked": {
+			reason: "If no observed policies are locked, no locked policy should be returned.",
+			observed: map[resource.Name]resource.ObservedComposed{
+				"immutability-container": observedPolicy(policy(365, false)),
+			},
+		},
+		"Strictest": {
+			reason: "The locked policy with the longest retention should be returned, including policies of containers no longer listed by the XR.",
+			observed: map[resource.Name]resource.ObservedComposed{
+				"account":                    {Resource: account},
+				"immutability-container-raw": observedPolicy(policy(90, true)),
+				"immutability-container-old": observedPolicy(policy(180, true)),
+				"immutability-container-new": observedPolicy(policy(365, false)),
+			},
+			want: &immutability{RetentionDays: 180, Locked: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := observedLockedImmutability(tc.observed)
+			if err != nil {
+				t.Fatalf("%s\nobservedLockedImmutability(...): unexpected error: %v", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nobservedLockedImmutability(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateImmutabilityChange(t *testing.T) {
+	locked := &immutability{RetentionDays: 365, Locked: true}
+
+	cases := map[string]struct {
+		reason    string
+		requested *immutability
+		locked    *immutability
+		want      error
+	}{
+		"NotLocked": {
+			reason:    "Unlocked policies should be free to change.",
+			requested: &immutability{RetentionDays: 1},
+		},
+		"Extended": {
+			reason:    "Locked policies should be free to extend their retention.",
+			requested: &immutability{RetentionDays: 730, Locked: true},
+			locked:    locked,
+		},
+		"Removed": {
+			reason: "Locked policies shouldn't be removed.",
+			locked: locked,
+			want:   errors.New("locked policies can't be removed"),
+		},
+		"Unlocked": {
+			reason:    "Locked policies shouldn't be unlocked.",
+			requested: &immutability{RetentionDays: 365},
+			locked:    locked,
+			want:      errors.New("locked policies can't be unlocked"),
+		},
+		"Shortened": {
+			reason:    "Locked policies shouldn't reduce their retention.",
+			requested: &immutability{RetentionDays: 30, Locked: true},
+			locked:    locked,
+			want:      errors.New("the retention of locked policies can't be reduced from 365 to 30 days"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateImmutabilityChange(tc.requested, tc.locked)
+
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nvalidateImmutabilityChange(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"slices"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// managementPolicyResourceName is the name of the composed resource for the
+// storage account's lifecycle management policy.
+const managementPolicyResourceName = "management-policy"
+
+// managementPolicy returns the lifecycle management policy requested by the
+// supplied parameters, or nil if no lifecycle rules are requested. The SKU is
+// used to reject rules the storage account can't support.
+func managementPolicy(lifecycle *v1alpha1.XStorageBucketSpecParametersLifecycle, s sku) (*storagev1beta1.ManagementPolicy, error) {
+	if lifecycle == nil || len(ptr.Deref(lifecycle.Rules, nil)) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]storagev1beta1.ManagementPolicySpecForProviderRuleItem, 0, len(*lifecycle.Rules))
+	for _, r := range *lifecycle.Rules {
+		if err := validateLifecycleRule(r, s); err != nil {
+			return nil, errors.Wrapf(err, "invalid lifecycle rule %q", ptr.Deref(r.Name, ""))
+		}
+		rules = append(rules, lifecycleRule(r))
+	}
+
+	return &storagev1beta1.ManagementPolicy{
+		APIVersion: ptr.To(storagev1beta1.ManagementPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.ManagementPolicyKindManagementPolicy),
+		Spec: &storagev1beta1.ManagementPolicySpec{
+			ForProvider: &storagev1beta1.ManagementPolicySpecForProvider{
+				Rule: &rules,
+				StorageAccountIDSelector: &storagev1beta1.ManagementPolicySpecForProviderStorageAccountIDSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}, nil
+}
+
+// validateLifecycleRule returns an error if the supplied rule has no actions,
+// or if Azure wouldn't accept its actions.
+func validateLifecycleRule(r v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem, s sku) error {
+	if r.TierToCoolAfterDays == nil && r.TierToColdAfterDays == nil && r.TierToArchiveAfterDays == nil && r.DeleteAfterDays == nil &&
+		r.DeleteVersionsAfterDays == nil && r.DeleteSnapshotsAfterDays == nil {
+		return errors.New("must specify at least one action")
+	}
+
+	// Blobs must move to successively colder tiers before they're deleted.
+	steps := []struct {
+		field string
+		days  *int
+	}{
+		{field: "tierToCoolAfterDays", days: r.TierToCoolAfterDays},
+		{field: "tierToColdAfterDays", days: r.TierToColdAfterDays},
+		{field: "tierToArchiveAfterDays", days: r.TierToArchiveAfterDays},
+		{field: "deleteAfterDays", days: r.DeleteAfterDays},
+	}
+	prev := -1
+	prevField := ""
+	for _, step := range steps {
+		if step.days == nil {
+			continue
+		}
+		if *step.days <= prev {
+			return errors.Errorf("%s must be greater than %s", step.field, prevField)
+		}
+		prev, prevField = *step.days, step.field
+	}
+
+	// Only standard accounts support moving blobs between access tiers.
+	if (r.TierToCoolAfterDays != nil || r.TierToColdAfterDays != nil || r.TierToArchiveAfterDays != nil) && s.Tier != v1alpha1.XStorageBucketSpecParametersTierStandard {
+		return errors.Errorf("%s storage accounts don't support access tiers; use only delete actions", s.Tier)
+	}
+
+	// The archive tier is only available to accounts that aren't
+	// zone-redundant.
+	if r.TierToArchiveAfterDays != nil {
+		if !slices.Contains([]v1alpha1.XStorageBucketSpecParametersReplication{
+			v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+			v1alpha1.XStorageBucketSpecParametersReplicationGRS,
+			v1alpha1.XStorageBucketSpecParametersReplicationRAGRS,
+		}, s.Replication) {
+			return errors.Errorf("storage accounts with %s replication don't support the archive tier", s.Replication)
+		}
+	}
+
+	return nil
+}
+
+// lifecycleRule returns the management policy rule for the supplied lifecycle
+// rule. Only block blobs are supported by lifecycle management.
+func lifecycleRule(r v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem) storagev1beta1.ManagementPolicySpecForProviderRuleItem {
+	actions := storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItem{}
+
+	if r.TierToCoolAfterDays != nil || r.TierToColdAfterDays != nil || r.TierToArchiveAfterDays != nil || r.DeleteAfterDays != nil {
+		actions.BaseBlob = &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemBaseBlobItem{{
+			TierToCoolAfterDaysSinceModificationGreaterThan:    days(r.TierToCoolAfterDays),
+			TierToColdAfterDaysSinceModificationGreaterThan:    days(r.TierToColdAfterDays),
+			TierToArchiveAfterDaysSinceModificationGreaterThan: days(r.TierToArchiveAfterDays),
+			DeleteAfterDaysSinceModificationGreaterThan:        days(r.DeleteAfterDays),
+		}}
+	}
+	if r.DeleteVersionsAfterDays != nil {
+		actions.Version = &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemVersionItem{{
+			DeleteAfterDaysSinceCreation: days(r.DeleteVersionsAfterDays),
+		}}
+	}
+	if r.DeleteSnapshotsAfterDays != nil {
+		actions.Snapshot = &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemSnapshotItem{{
+			DeleteAfterDaysSinceCreationGreaterThan: days(r.DeleteSnapshotsAfterDays),
+		}}
+	}
+
+	filters := storagev1beta1.ManagementPolicySpecForProviderRuleItemFiltersItem{
+		BlobTypes: &[]string{"blockBlob"},
+	}
+	if len(ptr.Deref(r.Prefixes, nil)) > 0 {
+		filters.PrefixMatch = r.Prefixes
+	}
+
+	return storagev1beta1.ManagementPolicySpecForProviderRuleItem{
+		Name:    r.Name,
+		Enabled: ptr.To(ptr.Deref(r.Enabled, true)),
+		Filters: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemFiltersItem{filters},
+		Actions: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItem{actions},
+	}
+}
+
+// days converts a number of days from the XR's integer representation to the
+// provider's floating point representation.
+func days(d *int) *float64 {
+	if d == nil {
+		return nil
+	}
+	return ptr.To(float64(*d))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestManagementPolicy(t *testing.T) {
+	standardLRS := sku{
+		Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+		Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+		Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+	}
+
+	type args struct {
+		lifecycle *v1alpha1.XStorageBucketSpecParametersLifecycle
+		sku       sku
+	}
+	type want struct {
+		policy *storagev1beta1.ManagementPolicy
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoLifecycle": {
+			reason: "If no lifecycle is requested, no management policy should be returned.",
+			args: args{
+				sku: standardLRS,
+			},
+			want: want{},
+		},
+		"NoRules": {
+			reason: "If a lifecycle without rules is requested, no management policy should be returned.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{},
+				},
+				sku: standardLRS,
+			},
+			want: want{},
+		},
+		"Rules": {
+			reason: "Each lifecycle rule should be converted to a management policy rule bound to the composed storage account.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{
+						{
+							Name:                   ptr.To("logs"),
+							Prefixes:               &[]string{"raw/logs/"},
+							TierToCoolAfterDays:    ptr.To(30),
+							TierToColdAfterDays:    ptr.To(90),
+							TierToArchiveAfterDays: ptr.To(180),
+							DeleteAfterDays:        ptr.To(365),
+						},
+						{
+							Name:                     ptr.To("cleanup"),
+							Enabled:                  ptr.To(false),
+							DeleteVersionsAfterDays:  ptr.To(7),
+							DeleteSnapshotsAfterDays: ptr.To(14),
+						},
+					},
+				},
+				sku: standardLRS,
+			},
+			want: want{
+				policy: &storagev1beta1.ManagementPolicy{
+					APIVersion: ptr.To(storagev1beta1.ManagementPolicyAPIVersionstorageAzureUpboundIoV1Beta1),
+					Kind:       ptr.To(storagev1beta1.ManagementPolicyKindManagementPolicy),
+					Spec: &storagev1beta1.ManagementPolicySpec{
+						ForProvider: &storagev1beta1.ManagementPolicySpecForProvider{
+							StorageAccountIDSelector: &storagev1beta1.ManagementPolicySpecForProviderStorageAccountIDSelector{
+								MatchControllerRef: ptr.To(true),
+							},
+							Rule: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItem{
+								{
+									Name:    ptr.To("logs"),
+									Enabled: ptr.To(true),
+									Filters: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemFiltersItem{{
+										BlobTypes:   &[]string{"blockBlob"},
+										PrefixMatch: &[]string{"raw/logs/"},
+									}},
+									Actions: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItem{{
+										BaseBlob: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemBaseBlobItem{{
+											TierToCoolAfterDaysSinceModificationGreaterThan:    ptr.To(30.0),
+											TierToColdAfterDaysSinceModificationGreaterThan:    ptr.To(90.0),
+											TierToArchiveAfterDaysSinceModificationGreaterThan: ptr.To(180.0),
+											DeleteAfterDaysSinceModificationGreaterThan:        ptr.To(365.0),
+										}},
+									}},
+								},
+								{
+									Name:    ptr.To("cleanup"),
+									Enabled: ptr.To(false),
+									Filters: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemFiltersItem{{
+										BlobTypes: &[]string{"blockBlob"},
+									}},
+									Actions: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItem{{
+										Version: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemVersionItem{{
+											DeleteAfterDaysSinceCreation: ptr.To(7.0),
+										}},
+										Snapshot: &[]storagev1beta1.ManagementPolicySpecForProviderRuleItemActionsItemSnapshotItem{{
+											DeleteAfterDaysSinceCreationGreaterThan: ptr.To(14.0),
+										}},
+									}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"NoActions": {
+			reason: "Rules without actions should be rejected.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{
+						{Name: ptr.To("empty"), Prefixes: &[]string{"tmp/"}},
+					},
+				},
+				sku: standardLRS,
+			},
+			want: want{
+				err: errors.Wrap(errors.New("must specify at least one action"), `invalid lifecycle rule "empty"`),
+			},
+		},
+		"DeleteBeforeTiering": {
+			reason: "Rules that delete blobs before moving them to a colder tier should be rejected.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{
+						{Name: ptr.To("logs"), TierToCoolAfterDays: ptr.To(30), DeleteAfterDays: ptr.To(30)},
+					},
+				},
+				sku: standardLRS,
+			},
+			want: want{
+				err: errors.Wrap(errors.New("deleteAfterDays must be greater than tierToCoolAfterDays"), `invalid lifecycle rule "logs"`),
+			},
+		},
+		"TieringPremium": {
+			reason: "Rules that move blobs to a colder tier should be rejected for premium accounts, which don't support access tiers.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{
+						{Name: ptr.To("logs"), TierToCoolAfterDays: ptr.To(30), DeleteAfterDays: ptr.To(90)},
+					},
+				},
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+				},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("Premium storage accounts don't support access tiers; use only delete actions"), `invalid lifecycle rule "logs"`),
+			},
+		},
+		"ArchiveZoneRedundant": {
+			reason: "Rules that move blobs to the archive tier should be rejected for zone-redundant accounts.",
+			args: args{
+				lifecycle: &v1alpha1.XStorageBucketSpecParametersLifecycle{
+					Rules: &[]v1alpha1.XStorageBucketSpecParametersLifecycleRulesItem{
+						{Name: ptr.To("logs"), TierToArchiveAfterDays: ptr.To(90)},
+					},
+				},
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationGZRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+				},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("storage accounts with GZRS replication don't support the archive tier"), `invalid lifecycle rule "logs"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := managementPolicy(tc.args.lifecycle, tc.args.sku)
+
+			if diff := cmp.Diff(tc.want.policy, got); diff != "" {
+				t.Errorf("%s\nmanagementPolicy(...): -want policy, +got policy:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nmanagementPolicy(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/alecthomas/kong"
 
 	"github.com/crossplane/function-sdk-go"
+	"github.com/crossplane/function-sdk-go/errors"
 )
 
 // CLI of this Function.
@@ -16,6 +17,13 @@ type CLI struct {
 	TLSCertsDir        string `help:"Directory containing server certs (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)" env:"TLS_SERVER_CERTS_DIR"`
 	Insecure           bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
 	MaxRecvMessageSize int    `help:"Maximum size of received messages in MB." default:"4"`
+
+	Cloud          string   `help:"Azure cloud to create buckets in. One of AzurePublicCloud, AzureUSGovernment or AzureChinaCloud." default:"AzurePublicCloud" env:"AZURE_CLOUD"`
+	AllowedRegions []string `help:"Azure regions buckets may be created in. If omitted, buckets may be created in every region of the cloud." env:"ALLOWED_REGIONS"`
+
+	RequiredTags []string `help:"Tags every bucket must set, such as owner and cost-center. Buckets that don't set them are rejected." env:"REQUIRED_TAGS"`
+
+	ConnectionSecretNamespace string `help:"Namespace composed resources write their connection secrets to, unless the XR writes its own connection secret to another namespace." default:"crossplane-system" env:"CONNECTION_SECRET_NAMESPACE"`
 }
 
 // Run this Function.
@@ -25,7 +33,18 @@ func (c *CLI) Run() error {
 		return err
 	}
 
-	return function.Serve(&Function{log: log},
+	regions, err := newRegionPolicy(c.Cloud, c.AllowedRegions)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range c.RequiredTags {
+		if err := validateTag(k, ""); err != nil {
+			return errors.Wrapf(err, "invalid required tag %q", k)
+		}
+	}
+
+	return function.Serve(&Function{log: log, regions: regions, requiredTags: c.RequiredTags, secretNamespace: c.ConnectionSecretNamespace},
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
 		function.Insecure(c.Insecure),
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Storage account names must be 3-24 character, lowercase alphanumeric strings
+// that are globally unique within Azure.
+const (
+	accountNameMinLength = 3
+	accountNameMaxLength = 24
+
+	// accountNameHashLength is the number of hex characters of the XR's UID
+	// hash appended to the account name to make it globally unique.
+	accountNameHashLength = 8
+)
+
+var accountNameInvalidChars = regexp.MustCompile("[^a-z0-9]")
+
+// An InvalidAccountNameError is returned when no valid storage account name can
+// be derived for an XR.
+type InvalidAccountNameError struct {
+	// Name of the XR the account name was derived from.
+	Name string
+
+	// Reason the derived account name is invalid.
+	Reason string
+}
+
+func (e *InvalidAccountNameError) Error() string {
+	return fmt.Sprintf("cannot derive a storage account name from %q: %s", e.Name, e.Reason)
+}
+
+// deriveAccountName derives a storage account name from the supplied XR name and UID.
+//
+// The name is lowercased and stripped of anything that isn't alphanumeric. When
+// a UID is supplied the name is truncated and suffixed with a hash of the UID,
+// which keeps it unique even when XR names collide or are truncated. Without a
+// UID the normalized name is only truncated, and must be long enough on its own.
+//
+// The compose-bucket-python and compose-bucket-go-templating functions derive
+// the same names, and must be kept in sync with this one.
+func deriveAccountName(name, uid string) (string, error) {
+	base := accountNameInvalidChars.ReplaceAllString(strings.ToLower(name), "")
+
+	if uid == "" {
+		if len(base) < accountNameMinLength {
+			return "", &InvalidAccountNameError{Name: name, Reason: fmt.Sprintf("must contain at least %d alphanumeric characters", accountNameMinLength)}
+		}
+		return truncate(base, accountNameMaxLength), nil
+	}
+
+	sum := sha256.Sum256([]byte(uid))
+	hash := hex.EncodeToString(sum[:])[:accountNameHashLength]
+
+	return truncate(base, accountNameMaxLength-accountNameHashLength) + hash, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeriveAccountName(t *testing.T) {
+	type args struct {
+		name string
+		uid  string
+	}
+	type want struct {
+		name string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoUID": {
+			reason: "Without a UID the normalized XR name should be used as is.",
+			args: args{
+				name: "example-xr",
+			},
+			want: want{
+				name: "examplexr",
+			},
+		},
+		"NoUIDNormalized": {
+			reason: "Uppercase letters should be lowercased and anything that isn't alphanumeric should be removed.",
+			args: args{
+				name: "My.Example-XR_1",
+			},
+			want: want{
+				name: "myexamplexr1",
+			},
+		},
+		"NoUIDTruncated": {
+			reason: "Without a UID, names longer than 24 characters should be truncated.",
+			args: args{
+				name: "a-really-long-example-storage-bucket-name",
+			},
+			want: want{
+				name: "areallylongexamplestorag",
+			},
+		},
+		"NoUIDTooShort": {
+			reason: "Without a UID, names with fewer than 3 alphanumeric characters should be rejected.",
+			args: args{
+				name: "a-b",
+			},
+			want: want{
+				err: &InvalidAccountNameError{Name: "a-b", Reason: "must contain at least 3 alphanumeric characters"},
+			},
+		},
+		"UID": {
+			reason: "With a UID, a hash of the UID should be appended to the normalized name.",
+			args: args{
+				name: "example-xr",
+				uid:  "2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d",
+			},
+			want: want{
+				name: "examplexre848635a",
+			},
+		},
+		"UIDTruncated": {
+			reason: "With a UID, long names should be truncated to make room for the hash.",
+			args: args{
+				name: "a-really-long-example-storage-bucket-name",
+				uid:  "2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d",
+			},
+			want: want{
+				name: "areallylongexampe848635a",
+			},
+		},
+		"UIDShortName": {
+			reason: "With a UID, short names are valid because the hash is long enough on its own.",
+			args: args{
+				name: "a",
+				uid:  "2f5ef6b0-6c9c-4c1b-9d3c-9f0e1b3c7a2d",
+			},
+			want: want{
+				name: "ae848635a",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := deriveAccountName(tc.args.name, tc.args.uid)
+
+			if diff := cmp.Diff(tc.want.name, got); diff != "" {
+				t.Errorf("%s\nderiveAccountName(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err); diff != "" {
+				t.Errorf("%s\nderiveAccountName(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
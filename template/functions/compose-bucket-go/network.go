@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// subnetIDPattern matches the Azure resource ID of a virtual network subnet.
+var subnetIDPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Network/virtualNetworks/[^/]+/subnets/[^/]+$`)
+
+// An accountNetwork holds the network settings of a storage account.
+type accountNetwork struct {
+	// PublicNetworkAccessEnabled is nil unless the XR configures it, in which
+	// case Azure's default of enabled applies.
+	PublicNetworkAccessEnabled *bool
+
+	// NetworkRules is nil unless the XR restricts which networks may reach
+	// the storage account.
+	NetworkRules *[]storagev1beta1.AccountSpecForProviderNetworkRulesItem
+}
+
+// accountNetworkFrom returns the storage account network settings requested by
+// the supplied parameters. Listing allowed IP ranges, subnets or bypasses
+// denies access from everywhere else.
+func accountNetworkFrom(network *v1alpha1.XStorageBucketSpecParametersNetwork) (accountNetwork, error) {
+	if network == nil {
+		return accountNetwork{}, nil
+	}
+
+	n := accountNetwork{PublicNetworkAccessEnabled: network.PublicNetworkAccessEnabled}
+	if network.AllowedIPRanges == nil && network.SubnetIds == nil && network.Bypass == nil {
+		return n, nil
+	}
+
+	ipRules := make([]string, 0, len(ptr.Deref(network.AllowedIPRanges, nil)))
+	for _, r := range ptr.Deref(network.AllowedIPRanges, nil) {
+		rule, err := ipRule(r)
+		if err != nil {
+			return accountNetwork{}, errors.Wrapf(err, "invalid allowed IP range %q", r)
+		}
+		ipRules = append(ipRules, rule)
+	}
+
+	subnetIDs := append([]string{}, ptr.Deref(network.SubnetIds, nil)...)
+	for _, id := range subnetIDs {
+		if !subnetIDPattern.MatchString(id) {
+			return accountNetwork{}, errors.Errorf("invalid subnet ID %q: must be the resource ID of a virtual network subnet", id)
+		}
+	}
+
+	// Azure lets its own trusted services through by default. None disables
+	// every bypass, so it can't be combined with others.
+	bypass := []string{string(v1alpha1.XStorageBucketSpecParametersNetworkBypassItemAzureServices)}
+	if network.Bypass != nil {
+		bypass = make([]string, 0, len(*network.Bypass))
+		for _, b := range *network.Bypass {
+			bypass = append(bypass, string(b))
+		}
+	}
+	if len(bypass) > 1 && slices.Contains(bypass, string(v1alpha1.XStorageBucketSpecParametersNetworkBypassItemNone)) {
+		return accountNetwork{}, errors.New("invalid bypass: None can't be combined with other bypasses")
+	}
+
+	n.NetworkRules = &[]storagev1beta1.AccountSpecForProviderNetworkRulesItem{{
+		DefaultAction:           ptr.To("Deny"),
+		Bypass:                  &bypass,
+		IPRules:                 &ipRules,
+		VirtualNetworkSubnetIds: &subnetIDs,
+	}}
+	return n, nil
+}
+
+// ipRule returns the storage account IP rule for the supplied IP address or
+// CIDR range. Azure only accepts public IPv4 addresses and ranges, and requires
+// single addresses to be written without a prefix length.
+func ipRule(r string) (string, error) {
+	r = strings.TrimSpace(r)
+	if r == "" {
+		return "", errors.New("must not be blank")
+	}
+
+	if !strings.Contains(r, "/") {
+		addr, err := netip.ParseAddr(r)
+		if err != nil {
+			return "", errors.New("must be an IPv4 address or CIDR range")
+		}
+		if err := validateIPRuleAddr(addr); err != nil {
+			return "", err
+		}
+		return addr.String(), nil
+	}
+
+	prefix, err := netip.ParsePrefix(r)
+	if err != nil {
+		return "", errors.New("must be an IPv4 address or CIDR range")
+	}
+	if err := validateIPRuleAddr(prefix.Addr()); err != nil {
+		return "", err
+	}
+	if prefix.Bits() > 30 {
+		return "", errors.Errorf("/%d ranges aren't supported; use a single address or a range of /30 or wider", prefix.Bits())
+	}
+	if prefix.Masked() != prefix {
+		return "", errors.Errorf("host bits must not be set; use %s", prefix.Masked())
+	}
+	return prefix.String(), nil
+}
+
+// validateIPRuleAddr returns an error if Azure wouldn't accept the supplied
+// address in an IP rule.
+func validateIPRuleAddr(addr netip.Addr) error {
+	if !addr.Is4() {
+		return errors.New("IPv6 addresses aren't supported")
+	}
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return errors.New("private addresses aren't supported; allow their subnet instead")
+	}
+	return nil
+}
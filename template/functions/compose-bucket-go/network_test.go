@@ -0,0 +1,168 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestAccountNetworkFrom(t *testing.T) {
+	subnetID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/hub/subnets/apps"
+
+	type want struct {
+		network accountNetwork
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		network *v1alpha1.XStorageBucketSpecParametersNetwork
+		want    want
+	}{
+		"NoNetwork": {
+			reason: "If no network isolation is requested, the storage account's network settings should be left to Azure.",
+			want:   want{},
+		},
+		"PublicNetworkAccessDisabled": {
+			reason: "Disabling public network access shouldn't require network rules.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				PublicNetworkAccessEnabled: ptr.To(false),
+			},
+			want: want{
+				network: accountNetwork{PublicNetworkAccessEnabled: ptr.To(false)},
+			},
+		},
+		"Allowlist": {
+			reason: "Allowed IP ranges and subnets should deny access from all other networks, except trusted Azure services.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				PublicNetworkAccessEnabled: ptr.To(true),
+				AllowedIPRanges:            &[]string{"203.0.113.7", " 198.51.100.0/24 "},
+				SubnetIds:                  &[]string{subnetID},
+			},
+			want: want{
+				network: accountNetwork{
+					PublicNetworkAccessEnabled: ptr.To(true),
+					NetworkRules: &[]storagev1beta1.AccountSpecForProviderNetworkRulesItem{{
+						DefaultAction:           ptr.To("Deny"),
+						Bypass:                  &[]string{"AzureServices"},
+						IPRules:                 &[]string{"203.0.113.7", "198.51.100.0/24"},
+						VirtualNetworkSubnetIds: &[]string{subnetID},
+					}},
+				},
+			},
+		},
+		"NoBypass": {
+			reason: "Disabling every bypass should deny access from all networks, including trusted Azure services.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				Bypass: &[]v1alpha1.XStorageBucketSpecParametersNetworkBypassItem{
+					v1alpha1.XStorageBucketSpecParametersNetworkBypassItemNone,
+				},
+			},
+			want: want{
+				network: accountNetwork{
+					NetworkRules: &[]storagev1beta1.AccountSpecForProviderNetworkRulesItem{{
+						DefaultAction:           ptr.To("Deny"),
+						Bypass:                  &[]string{"None"},
+						IPRules:                 &[]string{},
+						VirtualNetworkSubnetIds: &[]string{},
+					}},
+				},
+			},
+		},
+		"ConflictingBypass": {
+			reason: "None shouldn't be combined with other bypasses.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				Bypass: &[]v1alpha1.XStorageBucketSpecParametersNetworkBypassItem{
+					v1alpha1.XStorageBucketSpecParametersNetworkBypassItemNone,
+					v1alpha1.XStorageBucketSpecParametersNetworkBypassItemLogging,
+				},
+			},
+			want: want{
+				err: errors.New("invalid bypass: None can't be combined with other bypasses"),
+			},
+		},
+		"BlankIPRange": {
+			reason: "Blank IP ranges should be rejected.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"  "},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("must not be blank"), `invalid allowed IP range "  "`),
+			},
+		},
+		"MalformedIPRange": {
+			reason: "IP ranges that aren't addresses or CIDR ranges should be rejected.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"203.0.113.0/33"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("must be an IPv4 address or CIDR range"), `invalid allowed IP range "203.0.113.0/33"`),
+			},
+		},
+		"NarrowIPRange": {
+			reason: "/31 and /32 ranges should be rejected, because Azure doesn't support them.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"203.0.113.7/32"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("/32 ranges aren't supported; use a single address or a range of /30 or wider"), `invalid allowed IP range "203.0.113.7/32"`),
+			},
+		},
+		"HostBitsSet": {
+			reason: "CIDR ranges with host bits set should be rejected.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"198.51.100.7/24"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("host bits must not be set; use 198.51.100.0/24"), `invalid allowed IP range "198.51.100.7/24"`),
+			},
+		},
+		"PrivateIPRange": {
+			reason: "Private IP ranges should be rejected, because Azure only applies IP rules to public traffic.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"10.0.0.0/16"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("private addresses aren't supported; allow their subnet instead"), `invalid allowed IP range "10.0.0.0/16"`),
+			},
+		},
+		"IPv6Address": {
+			reason: "IPv6 addresses should be rejected, because Azure doesn't support them.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				AllowedIPRanges: &[]string{"2001:db8::1"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("IPv6 addresses aren't supported"), `invalid allowed IP range "2001:db8::1"`),
+			},
+		},
+		"InvalidSubnetID": {
+			reason: "Subnet IDs that aren't subnet resource IDs should be rejected.",
+			network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+				SubnetIds: &[]string{"apps"},
+			},
+			want: want{
+				err: errors.New(`invalid subnet ID "apps": must be the resource ID of a virtual network subnet`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := accountNetworkFrom(tc.network)
+
+			if diff := cmp.Diff(tc.want.network, got); diff != "" {
+				t.Errorf("%s\naccountNetworkFrom(...): -want network, +got network:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\naccountNetworkFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
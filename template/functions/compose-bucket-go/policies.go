@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// resourcePolicies are the deletion and management policies of a bucket's
+// composed resources. They apply to every composed resource, so that for
+// example an observe-only bucket never changes the encryption, lifecycle
+// rules or role assignments of the storage account it observes.
+type resourcePolicies struct {
+	// DeletionPolicy of the composed resources. Empty to use the provider's
+	// default, which deletes the external resource.
+	DeletionPolicy xpv1.DeletionPolicy
+
+	// ManagementPolicies of the composed resources. Nil to use the
+	// provider's default, which fully manages the external resource.
+	ManagementPolicies []xpv1.ManagementAction
+}
+
+// deletionPolicyFrom returns the deletion policy requested by the supplied
+// parameters, or an empty policy if none is requested.
+func deletionPolicyFrom(params *v1alpha1.XStorageBucketSpecParameters) (xpv1.DeletionPolicy, error) {
+	if params.DeletionPolicy == nil {
+		return "", nil
+	}
+	switch dp := xpv1.DeletionPolicy(*params.DeletionPolicy); dp {
+	case xpv1.DeletionDelete, xpv1.DeletionOrphan:
+		return dp, nil
+	default:
+		return "", errors.Errorf("unsupported deletion policy %q; use one of %s, %s", dp, xpv1.DeletionDelete, xpv1.DeletionOrphan)
+	}
+}
+
+// managementPoliciesFrom returns the management policies requested by the
+// supplied parameters, or nil if none are requested.
+func managementPoliciesFrom(params *v1alpha1.XStorageBucketSpecParameters) ([]xpv1.ManagementAction, error) {
+	if params.ManagementPolicies == nil {
+		return nil, nil
+	}
+
+	mps := make([]xpv1.ManagementAction, 0, len(*params.ManagementPolicies))
+	for _, mp := range *params.ManagementPolicies {
+		switch a := xpv1.ManagementAction(mp); a {
+		case xpv1.ManagementActionAll, xpv1.ManagementActionObserve, xpv1.ManagementActionCreate,
+			xpv1.ManagementActionUpdate, xpv1.ManagementActionDelete, xpv1.ManagementActionLateInitialize:
+			mps = append(mps, a)
+		default:
+			return nil, errors.Errorf("unsupported management policy %q", a)
+		}
+	}
+
+	// All actions includes every other action, and Crossplane can't manage
+	// a resource it can't observe.
+	if slices.Contains(mps, xpv1.ManagementActionAll) {
+		if len(mps) > 1 {
+			return nil, errors.Errorf("management policy %q must not be combined with other policies", xpv1.ManagementActionAll)
+		}
+	} else if !slices.Contains(mps, xpv1.ManagementActionObserve) {
+		return nil, errors.Errorf("management policies must include %q", xpv1.ManagementActionObserve)
+	}
+	return mps, nil
+}
+
+// Orphaned returns true if deleting the bucket leaves its data behind in
+// Azure, either because the deletion policy orphans it or because the
+// management policies don't allow Crossplane to delete it.
+func (p resourcePolicies) Orphaned() bool {
+	if p.DeletionPolicy == xpv1.DeletionOrphan {
+		return true
+	}
+	if p.ManagementPolicies == nil || slices.Contains(p.ManagementPolicies, xpv1.ManagementActionAll) {
+		return false
+	}
+	return !slices.Contains(p.ManagementPolicies, xpv1.ManagementActionDelete)
+}
+
+// ResourceGroup returns the policies of the composed resource group. Deleting
+// an Azure resource group deletes everything in it, so it's orphaned whenever
+// the bucket's data is, even if the requested deletion policy is Delete.
+func (p resourcePolicies) ResourceGroup() resourcePolicies {
+	rg := resourcePolicies{ManagementPolicies: p.ManagementPolicies}
+	if p.Orphaned() {
+		rg.DeletionPolicy = xpv1.DeletionOrphan
+	}
+	return rg
+}
+
+// Apply sets the policies of the supplied composed resource. Policies that
+// aren't set are left to the provider's defaults.
+func (p resourcePolicies) Apply(cd *composed.Unstructured) error {
+	if p.DeletionPolicy != "" {
+		if err := cd.SetString("spec.deletionPolicy", string(p.DeletionPolicy)); err != nil {
+			return errors.Wrap(err, "cannot set deletion policy")
+		}
+	}
+	if p.ManagementPolicies != nil {
+		mps := make([]any, 0, len(p.ManagementPolicies))
+		for _, a := range p.ManagementPolicies {
+			mps = append(mps, string(a))
+		}
+		if err := cd.SetValue("spec.managementPolicies", mps); err != nil {
+			return errors.Wrap(err, "cannot set management policies")
+		}
+	}
+	return nil
+}
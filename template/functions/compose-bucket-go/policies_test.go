@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestDeletionPolicyFrom(t *testing.T) {
+	type want struct {
+		dp  xpv1.DeletionPolicy
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		dp     *v1alpha1.XStorageBucketSpecParametersDeletionPolicy
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no deletion policy is requested, the provider's default should be used.",
+			want:   want{},
+		},
+		"Orphan": {
+			reason: "The Orphan deletion policy should retain data when the bucket is deleted.",
+			dp:     ptr.To(v1alpha1.XStorageBucketSpecParametersDeletionPolicyOrphan),
+			want:   want{dp: xpv1.DeletionOrphan},
+		},
+		"Unsupported": {
+			reason: "Deletion policies Crossplane doesn't support should be rejected.",
+			dp:     ptr.To(v1alpha1.XStorageBucketSpecParametersDeletionPolicy("Retain")),
+			want: want{
+				err: errors.New(`unsupported deletion policy "Retain"; use one of Delete, Orphan`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := deletionPolicyFrom(&v1alpha1.XStorageBucketSpecParameters{DeletionPolicy: tc.dp})
+
+			if diff := cmp.Diff(tc.want.dp, got); diff != "" {
+				t.Errorf("%s\ndeletionPolicyFrom(...): -want policy, +got policy:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ndeletionPolicyFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestManagementPoliciesFrom(t *testing.T) {
+	type want struct {
+		mps []xpv1.ManagementAction
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		mps    *[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no management policies are requested, the provider's default should be used.",
+			want:   want{},
+		},
+		"ObserveOnly": {
+			reason: "Observe-only management policies should be returned as is.",
+			mps:    &[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem{"Observe"},
+			want:   want{mps: []xpv1.ManagementAction{xpv1.ManagementActionObserve}},
+		},
+		"AllCombined": {
+			reason: "The * management policy should be rejected if combined with other policies, because it includes them.",
+			mps:    &[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem{"*", "Observe"},
+			want: want{
+				err: errors.New(`management policy "*" must not be combined with other policies`),
+			},
+		},
+		"WithoutObserve": {
+			reason: "Management policies that don't allow observing should be rejected.",
+			mps:    &[]v1alpha1.XStorageBucketSpecParametersManagementPoliciesItem{"Create", "Delete"},
+			want: want{
+				err: errors.New(`management policies must include "Observe"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := managementPoliciesFrom(&v1alpha1.XStorageBucketSpecParameters{ManagementPolicies: tc.mps})
+
+			if diff := cmp.Diff(tc.want.mps, got); diff != "" {
+				t.Errorf("%s\nmanagementPoliciesFrom(...): -want policies, +got policies:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nmanagementPoliciesFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResourceGroupPolicies(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		policies resourcePolicies
+		want     resourcePolicies
+	}{
+		"Default": {
+			reason:   "If the bucket's data is deleted with it, the resource group should use the provider's defaults.",
+			policies: resourcePolicies{},
+			want:     resourcePolicies{},
+		},
+		"Orphan": {
+			reason:   "If the bucket's data is orphaned, the resource group should be too.",
+			policies: resourcePolicies{DeletionPolicy: xpv1.DeletionOrphan},
+			want:     resourcePolicies{DeletionPolicy: xpv1.DeletionOrphan},
+		},
+		"WithoutDelete": {
+			reason:   "If Crossplane may not delete the bucket's data, the resource group should be orphaned, and managed like the bucket's other resources.",
+			policies: resourcePolicies{ManagementPolicies: []xpv1.ManagementAction{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate}},
+			want: resourcePolicies{
+				DeletionPolicy:     xpv1.DeletionOrphan,
+				ManagementPolicies: []xpv1.ManagementAction{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate},
+			},
+		},
+		"All": {
+			reason:   "If Crossplane may take all actions, the resource group shouldn't be orphaned.",
+			policies: resourcePolicies{ManagementPolicies: []xpv1.ManagementAction{xpv1.ManagementActionAll}},
+			want:     resourcePolicies{ManagementPolicies: []xpv1.ManagementAction{xpv1.ManagementActionAll}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.policies.ResourceGroup()
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nResourceGroup(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestApplyPolicies(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		policies resourcePolicies
+		want     map[string]any
+	}{
+		"Default": {
+			reason:   "Unset policies should be left to the provider's defaults.",
+			policies: resourcePolicies{},
+			want:     map[string]any{},
+		},
+		"Set": {
+			reason: "Set policies should be written to the composed resource's spec.",
+			policies: resourcePolicies{
+				DeletionPolicy:     xpv1.DeletionOrphan,
+				ManagementPolicies: []xpv1.ManagementAction{xpv1.ManagementActionObserve},
+			},
+			want: map[string]any{
+				"spec": map[string]any{
+					"deletionPolicy":     "Orphan",
+					"managementPolicies": []any{"Observe"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cd := composed.New()
+			if err := tc.policies.Apply(cd); err != nil {
+				t.Fatalf("%s\nApply(...): unexpected error: %v", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want, cd.Object); diff != "" {
+				t.Errorf("%s\nApply(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
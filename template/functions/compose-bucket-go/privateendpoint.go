@@ -0,0 +1,99 @@
+package main
+
+import (
+	"regexp"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	networkv1beta1 "dev.upbound.io/models/io/upbound/azure/network/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// privateEndpointResourceName is the name of the composed resource for the
+// storage account's private endpoint.
+const privateEndpointResourceName resource.Name = "private-endpoint"
+
+// privateEndpointSubresource is the storage account sub-resource the private
+// endpoint connects to.
+const privateEndpointSubresource = "blob"
+
+// privateDNSZoneIDPattern matches the Azure resource ID of a private DNS zone.
+var privateDNSZoneIDPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Network/privateDnsZones/[^/]+$`)
+
+// A bucketPrivateEndpoint is a private endpoint composed for an XR's blob
+// service.
+type bucketPrivateEndpoint struct {
+	// SubnetID of the subnet the endpoint's network interface is placed in.
+	SubnetID string
+
+	// PrivateDNSZoneID of the private DNS zone to register the endpoint's
+	// address in. Empty if the endpoint shouldn't be registered.
+	PrivateDNSZoneID string
+}
+
+// privateEndpointFrom returns the private endpoint requested by the supplied
+// parameters, or nil if no private endpoint is requested.
+func privateEndpointFrom(params *v1alpha1.XStorageBucketSpecParameters) (*bucketPrivateEndpoint, error) {
+	if params.PrivateEndpoint == nil || ptr.Deref(params.PrivateEndpoint.SubnetID, "") == "" {
+		return nil, nil
+	}
+
+	e := &bucketPrivateEndpoint{
+		SubnetID:         *params.PrivateEndpoint.SubnetID,
+		PrivateDNSZoneID: ptr.Deref(params.PrivateEndpoint.PrivateDNSZoneID, ""),
+	}
+	if !subnetIDPattern.MatchString(e.SubnetID) {
+		return nil, errors.Errorf("invalid subnet ID %q: must be the resource ID of a virtual network subnet", e.SubnetID)
+	}
+	if e.PrivateDNSZoneID != "" && !privateDNSZoneIDPattern.MatchString(e.PrivateDNSZoneID) {
+		return nil, errors.Errorf("invalid private DNS zone ID %q: must be the resource ID of a private DNS zone", e.PrivateDNSZoneID)
+	}
+
+	// Private buckets aren't reachable from public networks, so we refuse to
+	// silently override a request for public network access.
+	if params.Network != nil && ptr.Deref(params.Network.PublicNetworkAccessEnabled, false) {
+		return nil, errors.New("public network access can't be enabled for a bucket with a private endpoint")
+	}
+
+	return e, nil
+}
+
+// PrivateEndpoint returns the composed PrivateEndpoint connecting the supplied
+// storage account's blob service to the endpoint's subnet. If the endpoint has
+// a private DNS zone, the endpoint's address is registered in it through a
+// private DNS zone group.
+func (e bucketPrivateEndpoint) PrivateEndpoint(location *string, accountID string, group bucketResourceGroup, tags map[string]string) *networkv1beta1.PrivateEndpoint {
+	pe := &networkv1beta1.PrivateEndpoint{
+		APIVersion: ptr.To(networkv1beta1.PrivateEndpointAPIVersionnetworkAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(networkv1beta1.PrivateEndpointKindPrivateEndpoint),
+		Spec: &networkv1beta1.PrivateEndpointSpec{
+			ForProvider: &networkv1beta1.PrivateEndpointSpecForProvider{
+				Location: location,
+				SubnetID: ptr.To(e.SubnetID),
+				Tags:     &tags,
+				PrivateServiceConnection: &[]networkv1beta1.PrivateEndpointSpecForProviderPrivateServiceConnectionItem{{
+					Name:                        ptr.To(privateEndpointSubresource),
+					IsManualConnection:          ptr.To(false),
+					PrivateConnectionResourceID: ptr.To(accountID),
+					SubresourceNames:            &[]string{privateEndpointSubresource},
+				}},
+			},
+		},
+	}
+	if group.Name != "" {
+		pe.Spec.ForProvider.ResourceGroupName = ptr.To(group.Name)
+	} else {
+		pe.Spec.ForProvider.ResourceGroupNameSelector = &networkv1beta1.PrivateEndpointSpecForProviderResourceGroupNameSelector{
+			MatchControllerRef: ptr.To(true),
+		}
+	}
+	if e.PrivateDNSZoneID != "" {
+		pe.Spec.ForProvider.PrivateDNSZoneGroup = &[]networkv1beta1.PrivateEndpointSpecForProviderPrivateDNSZoneGroupItem{{
+			Name:              ptr.To("default"),
+			PrivateDNSZoneIds: &[]string{e.PrivateDNSZoneID},
+		}}
+	}
+	return pe
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestPrivateEndpointFrom(t *testing.T) {
+	subnetID := "/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/hub/subnets/endpoints"
+	zoneID := "/subscriptions/0000/resourceGroups/network/providers/Microsoft.Network/privateDnsZones/privatelink.blob.core.windows.net"
+
+	type want struct {
+		endpoint *bucketPrivateEndpoint
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If the XR doesn't set a private endpoint subnet, no private endpoint should be requested.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{},
+			},
+			want: want{},
+		},
+		"WithoutDNSZone": {
+			reason: "A private endpoint shouldn't require a private DNS zone.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+					SubnetID: ptr.To(subnetID),
+				},
+			},
+			want: want{
+				endpoint: &bucketPrivateEndpoint{SubnetID: subnetID},
+			},
+		},
+		"WithDNSZone": {
+			reason: "A private endpoint should be registered in the requested private DNS zone.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+					SubnetID:         ptr.To(subnetID),
+					PrivateDNSZoneID: ptr.To(zoneID),
+				},
+			},
+			want: want{
+				endpoint: &bucketPrivateEndpoint{SubnetID: subnetID, PrivateDNSZoneID: zoneID},
+			},
+		},
+		"InvalidSubnetID": {
+			reason: "Subnet IDs that aren't subnet resource IDs should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+					SubnetID: ptr.To("endpoints"),
+				},
+			},
+			want: want{
+				err: errors.New(`invalid subnet ID "endpoints": must be the resource ID of a virtual network subnet`),
+			},
+		},
+		"InvalidDNSZoneID": {
+			reason: "Private DNS zone IDs that aren't private DNS zone resource IDs should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+					SubnetID:         ptr.To(subnetID),
+					PrivateDNSZoneID: ptr.To("privatelink.blob.core.windows.net"),
+				},
+			},
+			want: want{
+				err: errors.New(`invalid private DNS zone ID "privatelink.blob.core.windows.net": must be the resource ID of a private DNS zone`),
+			},
+		},
+		"PublicNetworkAccessEnabled": {
+			reason: "Private endpoints shouldn't be combined with an explicit request for public network access.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Network: &v1alpha1.XStorageBucketSpecParametersNetwork{
+					PublicNetworkAccessEnabled: ptr.To(true),
+				},
+				PrivateEndpoint: &v1alpha1.XStorageBucketSpecParametersPrivateEndpoint{
+					SubnetID: ptr.To(subnetID),
+				},
+			},
+			want: want{
+				err: errors.New("public network access can't be enabled for a bucket with a private endpoint"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := privateEndpointFrom(tc.params)
+
+			if diff := cmp.Diff(tc.want.endpoint, got); diff != "" {
+				t.Errorf("%s\nprivateEndpointFrom(...): -want endpoint, +got endpoint:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nprivateEndpointFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
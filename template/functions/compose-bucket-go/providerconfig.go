@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// environmentContextKey is the context key of the environment loaded by
+// earlier steps of the pipeline, such as function-environment-configs.
+const environmentContextKey = "apiextensions.crossplane.io/environment"
+
+// environmentKeyProviderConfigName is the key of the environment whose value
+// is the name of the ProviderConfig to use unless the XR names one.
+const environmentKeyProviderConfigName = "providerConfigName"
+
+// providerConfigRequirementName is the name of the required resource that
+// holds the ProviderConfig of the composed resources.
+const providerConfigRequirementName = "provider-config"
+
+// The ProviderConfig of the Azure providers.
+const (
+	providerConfigAPIVersion = "azure.upbound.io/v1beta1"
+	providerConfigKind       = "ProviderConfig"
+)
+
+// providerConfigNamePattern matches a Kubernetes object name.
+var providerConfigNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// providerConfigNameFrom returns the name of the ProviderConfig the composed
+// resources should use. The XR's providerConfigName parameter takes
+// precedence over the environment. If neither names a ProviderConfig, an
+// empty name is returned and the composed resources use the providers'
+// default ProviderConfig.
+func providerConfigNameFrom(req *fnv1.RunFunctionRequest, params *v1alpha1.XStorageBucketSpecParameters) (string, error) {
+	name := ptr.Deref(params.ProviderConfigName, "")
+	if name == "" {
+		if env, ok := request.GetContextKey(req, environmentContextKey); ok {
+			name = env.GetStructValue().GetFields()[environmentKeyProviderConfigName].GetStringValue()
+		}
+	}
+	if name == "" {
+		return "", nil
+	}
+	if len(name) > 253 || !providerConfigNamePattern.MatchString(name) {
+		return "", errors.Errorf("invalid ProviderConfig name %q: must be a Kubernetes object name", name)
+	}
+	return name, nil
+}
+
+// requireProviderConfig requires the named ProviderConfig. It returns the
+// matching ProviderConfigs, and false if Crossplane hasn't yet looked them up.
+func requireProviderConfig(req *fnv1.RunFunctionRequest, rsp *fnv1.RunFunctionResponse, name string) ([]resource.Extra, bool, error) {
+	if rsp.Requirements == nil {
+		rsp.Requirements = &fnv1.Requirements{}
+	}
+	if rsp.Requirements.ExtraResources == nil {
+		rsp.Requirements.ExtraResources = make(map[string]*fnv1.ResourceSelector)
+	}
+	rsp.Requirements.ExtraResources[providerConfigRequirementName] = &fnv1.ResourceSelector{
+		ApiVersion: providerConfigAPIVersion,
+		Kind:       providerConfigKind,
+		Match:      &fnv1.ResourceSelector_MatchName{MatchName: name},
+	}
+
+	extras, err := request.GetExtraResources(req)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "cannot get required resources")
+	}
+	pcs, ok := extras[providerConfigRequirementName]
+	return pcs, ok, nil
+}
+
+// setProviderConfigRef sets the ProviderConfig of the supplied composed
+// resource. An empty name leaves it to the provider's default.
+func setProviderConfigRef(cd *composed.Unstructured, name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := cd.SetString("spec.providerConfigRef.name", name); err != nil {
+		return errors.Wrap(err, "cannot set ProviderConfig reference")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+)
+
+func TestProviderConfigNameFrom(t *testing.T) {
+	environment, _ := structpb.NewStruct(map[string]any{
+		"apiextensions.crossplane.io/environment": map[string]any{
+			"providerConfigName": "subscription-b",
+		},
+	})
+
+	type args struct {
+		req    *fnv1.RunFunctionRequest
+		params *v1alpha1.XStorageBucketSpecParameters
+	}
+	type want struct {
+		name string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If neither the XR nor the environment names a ProviderConfig, the providers' default should be used.",
+			args: args{
+				req:    &fnv1.RunFunctionRequest{},
+				params: &v1alpha1.XStorageBucketSpecParameters{},
+			},
+			want: want{},
+		},
+		"Parameter": {
+			reason: "The XR's ProviderConfig should take precedence over the environment's.",
+			args: args{
+				req:    &fnv1.RunFunctionRequest{Context: environment},
+				params: &v1alpha1.XStorageBucketSpecParameters{ProviderConfigName: ptr.To("subscription-a")},
+			},
+			want: want{name: "subscription-a"},
+		},
+		"Environment": {
+			reason: "If the XR doesn't name a ProviderConfig, the environment's should be used.",
+			args: args{
+				req:    &fnv1.RunFunctionRequest{Context: environment},
+				params: &v1alpha1.XStorageBucketSpecParameters{},
+			},
+			want: want{name: "subscription-b"},
+		},
+		"InvalidName": {
+			reason: "ProviderConfig names that aren't Kubernetes object names should be rejected.",
+			args: args{
+				req:    &fnv1.RunFunctionRequest{},
+				params: &v1alpha1.XStorageBucketSpecParameters{ProviderConfigName: ptr.To("Subscription A")},
+			},
+			want: want{
+				err: errors.New(`invalid ProviderConfig name "Subscription A": must be a Kubernetes object name`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := providerConfigNameFrom(tc.args.req, tc.args.params)
+
+			if diff := cmp.Diff(tc.want.name, got); diff != "" {
+				t.Errorf("%s\nproviderConfigNameFrom(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nproviderConfigNameFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
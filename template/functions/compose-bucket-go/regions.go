@@ -0,0 +1,170 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Azure clouds. Sovereign clouds have their own regions, which aren't
+// available in the public cloud, and vice versa.
+const (
+	cloudPublic       = "AzurePublicCloud"
+	cloudUSGovernment = "AzureUSGovernment"
+	cloudChina        = "AzureChinaCloud"
+)
+
+// regionCatalogs are the names of the regions of each Azure cloud. New regions
+// must be added here before buckets can be created in them.
+var regionCatalogs = map[string][]string{
+	cloudPublic: {
+		"australiacentral", "australiacentral2", "australiaeast", "australiasoutheast",
+		"brazilsouth", "brazilsoutheast",
+		"canadacentral", "canadaeast",
+		"centralindia", "centralus",
+		"chilecentral",
+		"eastasia", "eastus", "eastus2",
+		"francecentral", "francesouth",
+		"germanynorth", "germanywestcentral",
+		"indonesiacentral",
+		"israelcentral",
+		"italynorth",
+		"japaneast", "japanwest",
+		"jioindiacentral", "jioindiawest",
+		"koreacentral", "koreasouth",
+		"malaysiawest",
+		"mexicocentral",
+		"newzealandnorth",
+		"northcentralus", "northeurope",
+		"norwayeast", "norwaywest",
+		"polandcentral",
+		"qatarcentral",
+		"southafricanorth", "southafricawest",
+		"southcentralus", "southeastasia", "southindia",
+		"spaincentral",
+		"swedencentral",
+		"switzerlandnorth", "switzerlandwest",
+		"uaecentral", "uaenorth",
+		"uksouth", "ukwest",
+		"westcentralus", "westeurope", "westindia", "westus", "westus2", "westus3",
+	},
+	cloudUSGovernment: {
+		"usdodcentral", "usdodeast",
+		"usgovarizona", "usgovtexas", "usgovvirginia",
+	},
+	cloudChina: {
+		"chinaeast", "chinaeast2", "chinaeast3",
+		"chinanorth", "chinanorth2", "chinanorth3",
+	},
+}
+
+// maxRegionSuggestionDistance is the largest edit distance between an unknown
+// region and a known region for the known region to be suggested instead.
+const maxRegionSuggestionDistance = 3
+
+// A regionPolicy determines which Azure regions buckets may be created in.
+// It's configured once for the whole function, so that an organization can
+// restrict the regions its buckets are created in.
+type regionPolicy struct {
+	// Cloud whose regions buckets may be created in. The zero value is the
+	// public cloud.
+	Cloud string
+
+	// Allowed regions. If empty, every region of the cloud is allowed.
+	Allowed []string
+}
+
+// newRegionPolicy returns a policy that allows buckets to be created in the
+// supplied regions of the supplied cloud. The regions may be display names.
+func newRegionPolicy(cloud string, allowed []string) (regionPolicy, error) {
+	if _, ok := regionCatalogs[cloud]; !ok {
+		return regionPolicy{}, errors.Errorf("unknown Azure cloud %q; use one of %s, %s, %s", cloud, cloudPublic, cloudUSGovernment, cloudChina)
+	}
+
+	p := regionPolicy{Cloud: cloud}
+	for _, region := range allowed {
+		name, err := p.normalize(region)
+		if err != nil {
+			return regionPolicy{}, errors.Wrap(err, "invalid allowed region")
+		}
+		p.Allowed = append(p.Allowed, name)
+	}
+	slices.Sort(p.Allowed)
+	p.Allowed = slices.Compact(p.Allowed)
+	return p, nil
+}
+
+// Location returns the name of the supplied region, which may be a display
+// name like "East US", or an error if buckets may not be created in it.
+func (p regionPolicy) Location(location string) (string, error) {
+	name, err := p.normalize(location)
+	if err != nil {
+		return "", err
+	}
+	if len(p.Allowed) > 0 && !slices.Contains(p.Allowed, name) {
+		return "", errors.Errorf("region %q isn't allowed; use one of %s", name, strings.Join(p.Allowed, ", "))
+	}
+	return name, nil
+}
+
+// normalize returns the name of the supplied region of the policy's cloud.
+// Display names are normalized by lowercasing them and removing everything
+// that isn't a letter or digit, which turns "East US 2" into "eastus2".
+func (p regionPolicy) normalize(region string) (string, error) {
+	cloud := p.Cloud
+	if cloud == "" {
+		cloud = cloudPublic
+	}
+	catalog := regionCatalogs[cloud]
+
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, region)
+	if slices.Contains(catalog, name) {
+		return name, nil
+	}
+
+	if suggestion := closestRegion(catalog, name); suggestion != "" {
+		return "", errors.Errorf("unknown %s region %q; did you mean %q?", cloud, region, suggestion)
+	}
+	return "", errors.Errorf("unknown %s region %q; list its regions with \"az account list-locations\"", cloud, region)
+}
+
+// closestRegion returns the region of the supplied catalog that's closest to
+// the supplied name, or an empty string if no region is close enough to
+// suggest. Ties are broken by catalog order.
+func closestRegion(catalog []string, name string) string {
+	closest, distance := "", maxRegionSuggestionDistance+1
+	for _, region := range catalog {
+		if d := editDistance(name, region); d < distance {
+			closest, distance = region, d
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between the supplied strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
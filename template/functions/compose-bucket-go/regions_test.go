@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestNewRegionPolicy(t *testing.T) {
+	type args struct {
+		cloud   string
+		allowed []string
+	}
+	type want struct {
+		policy regionPolicy
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"AllowedRegions": {
+			reason: "Allowed regions should be normalized, sorted and deduplicated.",
+			args: args{
+				cloud:   cloudPublic,
+				allowed: []string{"West Europe", "northeurope", "westeurope"},
+			},
+			want: want{
+				policy: regionPolicy{Cloud: cloudPublic, Allowed: []string{"northeurope", "westeurope"}},
+			},
+		},
+		"UnknownCloud": {
+			reason: "Clouds without a region catalog should be rejected.",
+			args: args{
+				cloud: "AzureGermanCloud",
+			},
+			want: want{
+				err: errors.New(`unknown Azure cloud "AzureGermanCloud"; use one of AzurePublicCloud, AzureUSGovernment, AzureChinaCloud`),
+			},
+		},
+		"AllowedRegionOfAnotherCloud": {
+			reason: "Allowed regions that aren't part of the cloud should be rejected.",
+			args: args{
+				cloud:   cloudUSGovernment,
+				allowed: []string{"eastus"},
+			},
+			want: want{
+				err: errors.Wrap(errors.New(`unknown AzureUSGovernment region "eastus"; list its regions with "az account list-locations"`), "invalid allowed region"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := newRegionPolicy(tc.args.cloud, tc.args.allowed)
+
+			if diff := cmp.Diff(tc.want.policy, got); diff != "" {
+				t.Errorf("%s\nnewRegionPolicy(...): -want policy, +got policy:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nnewRegionPolicy(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRegionPolicyLocation(t *testing.T) {
+	type want struct {
+		location string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		policy   regionPolicy
+		location string
+		want     want
+	}{
+		"Name": {
+			reason:   "Region names should be returned as is.",
+			location: "eastus2",
+			want:     want{location: "eastus2"},
+		},
+		"DisplayName": {
+			reason:   "Display names should be normalized to region names.",
+			location: "East US 2",
+			want:     want{location: "eastus2"},
+		},
+		"Misspelled": {
+			reason:   "Unknown regions that are close to a known region should be rejected with a suggestion.",
+			location: "westeurop",
+			want: want{
+				err: errors.New(`unknown AzurePublicCloud region "westeurop"; did you mean "westeurope"?`),
+			},
+		},
+		"OtherProvider": {
+			reason:   "Regions of other cloud providers should be rejected.",
+			location: "us-east-1",
+			want: want{
+				err: errors.New(`unknown AzurePublicCloud region "us-east-1"; list its regions with "az account list-locations"`),
+			},
+		},
+		"SovereignCloud": {
+			reason:   "Regions of sovereign clouds should be looked up in their own catalogs.",
+			policy:   regionPolicy{Cloud: cloudChina},
+			location: "China North 3",
+			want:     want{location: "chinanorth3"},
+		},
+		"NotAllowed": {
+			reason:   "Regions the policy doesn't allow should be rejected.",
+			policy:   regionPolicy{Cloud: cloudPublic, Allowed: []string{"northeurope", "westeurope"}},
+			location: "East US",
+			want: want{
+				err: errors.New(`region "eastus" isn't allowed; use one of northeurope, westeurope`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.policy.Location(tc.location)
+
+			if diff := cmp.Diff(tc.want.location, got); diff != "" {
+				t.Errorf("%s\nLocation(...): -want location, +got location:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nLocation(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
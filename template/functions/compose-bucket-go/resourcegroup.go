@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// resourceGroupResourceName is the name of the composed resource group.
+const resourceGroupResourceName = "rg"
+
+// Resource group names are 1-90 alphanumeric, underscore, hyphen, period and
+// parenthesis characters, and can't end with a period.
+var resourceGroupNamePattern = regexp.MustCompile(`^[-\w.()]{0,89}[-\w()]$`)
+
+// A bucketResourceGroup is the resource group the bucket's Azure resources
+// are created in.
+type bucketResourceGroup struct {
+	// Name of an existing resource group. If empty, the resources are created
+	// in the composed resource group, which they select by controller
+	// reference.
+	Name string
+
+	// Compose the resource group. A composed resource group is kept once it
+	// exists, because deleting it would delete everything in it.
+	Compose bool
+
+	// Notice explains why the requested resource group isn't used, if it
+	// isn't.
+	Notice string
+}
+
+// resourceGroupFrom returns the resource group requested by the supplied
+// parameters. Storage accounts can't be moved to another resource group
+// without being recreated, so once the account exists it stays in the group
+// it was created in, regardless of the parameters.
+func resourceGroupFrom(params *v1alpha1.XStorageBucketSpecParameters, observed map[resource.Name]resource.ObservedComposed) (bucketResourceGroup, error) {
+	requested := ptr.Deref(params.ResourceGroupName, "")
+	if requested != "" && !resourceGroupNamePattern.MatchString(requested) {
+		return bucketResourceGroup{}, errors.Errorf("invalid resource group name %q: must be 1-90 alphanumeric, underscore, hyphen, period or parenthesis characters, and must not end with a period", requested)
+	}
+
+	composed := isObserved(observed, resourceGroupResourceName)
+
+	oc, ok := observed["account"]
+	if !ok {
+		return bucketResourceGroup{Name: requested, Compose: requested == "" || composed}, nil
+	}
+
+	account := &storagev1beta1.Account{}
+	if err := convertViaJSON(account, oc.Resource); err != nil {
+		return bucketResourceGroup{}, errors.Wrap(err, "cannot convert observed account")
+	}
+	var existing string
+	if account.Spec != nil && account.Spec.ForProvider != nil {
+		existing = ptr.Deref(account.Spec.ForProvider.ResourceGroupName, "")
+	}
+
+	// Accounts in the composed resource group have their group name resolved
+	// from their selector, if it's been resolved at all.
+	if existing == "" || (composed && existing == meta.GetExternalName(observed[resourceGroupResourceName].Resource)) {
+		group := bucketResourceGroup{Compose: true}
+		if requested != "" {
+			group.Notice = "Keeping the storage account in its composed resource group: changing resourceGroupName would recreate the storage account and delete its data"
+		}
+		return group, nil
+	}
+
+	group := bucketResourceGroup{Name: existing, Compose: composed}
+	if requested != existing {
+		group.Notice = fmt.Sprintf("Keeping the storage account in resource group %q: changing resourceGroupName would recreate the storage account and delete its data", existing)
+	}
+	return group, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	azv1beta1 "dev.upbound.io/models/io/upbound/azure/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestResourceGroupFrom(t *testing.T) {
+	observedAccount := func(group string) resource.ObservedComposed {
+		c := composed.New()
+		_ = convertViaJSON(c, &storagev1beta1.Account{
+			APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+			Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+			Spec: &storagev1beta1.AccountSpec{
+				ForProvider: &storagev1beta1.AccountSpecForProvider{
+					ResourceGroupName: ptr.To(group),
+				},
+			},
+		})
+		return resource.ObservedComposed{Resource: c}
+	}
+	observedGroup := func(name string) resource.ObservedComposed {
+		c := composed.New()
+		_ = convertViaJSON(c, &azv1beta1.ResourceGroup{
+			APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+			Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+			Metadata: &metav1.ObjectMeta{
+				Annotations: &map[string]string{
+					"crossplane.io/external-name": name,
+				},
+			},
+		})
+		return resource.ObservedComposed{Resource: c}
+	}
+
+	type args struct {
+		params   *v1alpha1.XStorageBucketSpecParameters
+		observed map[resource.Name]resource.ObservedComposed
+	}
+	type want struct {
+		group bucketResourceGroup
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Composed": {
+			reason: "If no resource group is requested, the resource group should be composed.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{},
+			},
+			want: want{
+				group: bucketResourceGroup{Compose: true},
+			},
+		},
+		"Existing": {
+			reason: "If a resource group is requested, the resource group shouldn't be composed.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					ResourceGroupName: ptr.To("team-a-storage"),
+				},
+			},
+			want: want{
+				group: bucketResourceGroup{Name: "team-a-storage"},
+			},
+		},
+		"ExistingAfterComposed": {
+			reason: "If a resource group is requested before the storage account exists, a composed resource group should be kept rather than deleted.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					ResourceGroupName: ptr.To("team-a-storage"),
+				},
+				observed: map[resource.Name]resource.ObservedComposed{
+					"rg": observedGroup("super-group"),
+				},
+			},
+			want: want{
+				group: bucketResourceGroup{Name: "team-a-storage", Compose: true},
+			},
+		},
+		"AccountInComposedGroup": {
+			reason: "If the storage account exists in the composed resource group, it should stay there even if another resource group is requested.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					ResourceGroupName: ptr.To("team-a-storage"),
+				},
+				observed: map[resource.Name]resource.ObservedComposed{
+					"rg":      observedGroup("super-group"),
+					"account": observedAccount("super-group"),
+				},
+			},
+			want: want{
+				group: bucketResourceGroup{
+					Compose: true,
+					Notice:  "Keeping the storage account in its composed resource group: changing resourceGroupName would recreate the storage account and delete its data",
+				},
+			},
+		},
+		"AccountInExistingGroup": {
+			reason: "If the storage account exists in an existing resource group, it should stay there even if the resource group is no longer requested.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{},
+				observed: map[resource.Name]resource.ObservedComposed{
+					"account": observedAccount("team-a-storage"),
+				},
+			},
+			want: want{
+				group: bucketResourceGroup{
+					Name:   "team-a-storage",
+					Notice: `Keeping the storage account in resource group "team-a-storage": changing resourceGroupName would recreate the storage account and delete its data`,
+				},
+			},
+		},
+		"InvalidName": {
+			reason: "Resource group names Azure doesn't accept should be rejected.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					ResourceGroupName: ptr.To("team-a."),
+				},
+			},
+			want: want{
+				err: errors.New(`invalid resource group name "team-a.": must be 1-90 alphanumeric, underscore, hyphen, period or parenthesis characters, and must not end with a period`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resourceGroupFrom(tc.args.params, tc.args.observed)
+
+			if diff := cmp.Diff(tc.want.group, got); diff != "" {
+				t.Errorf("%s\nresourceGroupFrom(...): -want group, +got group:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nresourceGroupFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Prefixes of the names of the composed resources for the queues, tables and
+// file shares an XR lists. Like containers, the rest of the name is the
+// queue's, table's or share's name.
+const (
+	queueResourceNamePrefix = "queue-"
+	tableResourceNamePrefix = "table-"
+	shareResourceNamePrefix = "share-"
+)
+
+// Queue, table and share names are 3-63 characters long.
+const (
+	serviceNameMinLength = 3
+	serviceNameMaxLength = 63
+)
+
+var (
+	// Queue and share names are lowercase alphanumeric strings. They may
+	// contain single hyphens, but not start or end with them.
+	queueNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	shareNamePattern = queueNamePattern
+
+	// Table names are alphanumeric strings that start with a letter.
+	tableNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+)
+
+// Quotas of file shares, in GiB. Larger shares require the storage account to
+// enable large file shares, which can't be disabled again.
+const (
+	shareMinQuota = 1
+	shareMaxQuota = 5120
+)
+
+// defaultShareAccessTier is the access tier of file shares unless the XR
+// requests another tier. It's Azure's own default.
+const defaultShareAccessTier = v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTierTransactionOptimized
+
+// A bucketQueue is a storage queue composed for an XR.
+type bucketQueue struct {
+	// ResourceName of the composed Queue.
+	ResourceName resource.Name
+
+	// Name of the queue in Azure.
+	Name string
+
+	// Metadata to assign to the queue.
+	Metadata *map[string]string
+}
+
+// A bucketTable is a storage table composed for an XR.
+type bucketTable struct {
+	// ResourceName of the composed Table.
+	ResourceName resource.Name
+
+	// Name of the table in Azure.
+	Name string
+}
+
+// A bucketShare is a file share composed for an XR.
+type bucketShare struct {
+	// ResourceName of the composed Share.
+	ResourceName resource.Name
+
+	// Name of the share in Azure.
+	Name string
+
+	// Quota of the share, in GiB.
+	Quota int
+
+	// AccessTier of the share: TransactionOptimized, Hot or Cool.
+	AccessTier string
+
+	// Metadata to assign to the share.
+	Metadata *map[string]string
+}
+
+// validateServiceSKU returns an error if storage accounts of the supplied SKU
+// don't support the named service. Only standard general-purpose v2 accounts
+// support queues, tables and file shares.
+func validateServiceSKU(s sku, service string) error {
+	if s.Tier != v1alpha1.XStorageBucketSpecParametersTierStandard || s.Kind != v1alpha1.XStorageBucketSpecParametersKindStorageV2 {
+		return errors.Errorf("%s %s storage accounts don't support %s; use a Standard StorageV2 storage account", s.Tier, s.Kind, service)
+	}
+	return nil
+}
+
+// validServiceName returns true if the supplied queue, table or share name
+// is of a valid length and matches the supplied pattern.
+func validServiceName(name string, pattern *regexp.Regexp) bool {
+	return len(name) >= serviceNameMinLength && len(name) <= serviceNameMaxLength && pattern.MatchString(name)
+}
+
+// queuesFrom returns the queues requested by the supplied parameters.
+func queuesFrom(params *v1alpha1.XStorageBucketSpecParameters, s sku) ([]bucketQueue, error) {
+	if len(ptr.Deref(params.Queues, nil)) == 0 {
+		return nil, nil
+	}
+	if err := validateServiceSKU(s, "queues"); err != nil {
+		return nil, err
+	}
+
+	queues := make([]bucketQueue, 0, len(*params.Queues))
+	seen := make(map[string]bool)
+	for _, q := range *params.Queues {
+		name := ptr.Deref(q.Name, "")
+		if !validServiceName(name, queueNamePattern) {
+			return nil, errors.Errorf("invalid queue name %q: must be 3-63 lowercase alphanumeric characters or single hyphens, and must start and end with a letter or number", name)
+		}
+		if seen[name] {
+			return nil, errors.Errorf("duplicate queue name %q", name)
+		}
+		seen[name] = true
+		queues = append(queues, bucketQueue{
+			ResourceName: resource.Name(queueResourceNamePrefix + name),
+			Name:         name,
+			Metadata:     q.Metadata,
+		})
+	}
+	return queues, nil
+}
+
+// tablesFrom returns the tables requested by the supplied parameters.
+func tablesFrom(params *v1alpha1.XStorageBucketSpecParameters, s sku) ([]bucketTable, error) {
+	if len(ptr.Deref(params.Tables, nil)) == 0 {
+		return nil, nil
+	}
+	if err := validateServiceSKU(s, "tables"); err != nil {
+		return nil, err
+	}
+
+	tables := make([]bucketTable, 0, len(*params.Tables))
+	seen := make(map[string]bool)
+	for _, t := range *params.Tables {
+		name := ptr.Deref(t.Name, "")
+		if !validServiceName(name, tableNamePattern) {
+			return nil, errors.Errorf("invalid table name %q: must be 3-63 alphanumeric characters, and must start with a letter", name)
+		}
+		// Table names are case-insensitive.
+		if seen[strings.ToLower(name)] {
+			return nil, errors.Errorf("duplicate table name %q", name)
+		}
+		seen[strings.ToLower(name)] = true
+		tables = append(tables, bucketTable{
+			ResourceName: resource.Name(tableResourceNamePrefix + name),
+			Name:         name,
+		})
+	}
+	return tables, nil
+}
+
+// sharesFrom returns the file shares requested by the supplied parameters.
+func sharesFrom(params *v1alpha1.XStorageBucketSpecParameters, s sku) ([]bucketShare, error) {
+	if len(ptr.Deref(params.FileShares, nil)) == 0 {
+		return nil, nil
+	}
+	if err := validateServiceSKU(s, "file shares"); err != nil {
+		return nil, err
+	}
+
+	shares := make([]bucketShare, 0, len(*params.FileShares))
+	seen := make(map[string]bool)
+	for _, fs := range *params.FileShares {
+		name := ptr.Deref(fs.Name, "")
+		if !validServiceName(name, shareNamePattern) {
+			return nil, errors.Errorf("invalid file share name %q: must be 3-63 lowercase alphanumeric characters or single hyphens, and must start and end with a letter or number", name)
+		}
+		if seen[name] {
+			return nil, errors.Errorf("duplicate file share name %q", name)
+		}
+		seen[name] = true
+
+		quota := ptr.Deref(fs.Quota, 0)
+		if quota < shareMinQuota || quota > shareMaxQuota {
+			return nil, errors.Errorf("invalid quota of file share %q: must be between %d and %d GiB", name, shareMinQuota, shareMaxQuota)
+		}
+
+		tier := ptr.Deref(fs.AccessTier, defaultShareAccessTier)
+		switch tier {
+		case v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTierTransactionOptimized,
+			v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTierHot,
+			v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTierCool:
+		default:
+			return nil, errors.Errorf("invalid access tier of file share %q: unsupported tier %q; use one of TransactionOptimized, Hot, Cool", name, tier)
+		}
+
+		shares = append(shares, bucketShare{
+			ResourceName: resource.Name(shareResourceNamePrefix + name),
+			Name:         name,
+			Quota:        quota,
+			AccessTier:   string(tier),
+			Metadata:     fs.Metadata,
+		})
+	}
+	return shares, nil
+}
+
+// Queue returns the composed Queue for the bucket queue.
+func (q bucketQueue) Queue() *storagev1beta1.Queue {
+	return &storagev1beta1.Queue{
+		APIVersion: ptr.To(storagev1beta1.QueueAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.QueueKindQueue),
+		Metadata:   externalName(q.Name),
+		Spec: &storagev1beta1.QueueSpec{
+			ForProvider: &storagev1beta1.QueueSpecForProvider{
+				Metadata: q.Metadata,
+				StorageAccountNameSelector: &storagev1beta1.QueueSpecForProviderStorageAccountNameSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}
+}
+
+// Table returns the composed Table for the bucket table.
+func (t bucketTable) Table() *storagev1beta1.Table {
+	return &storagev1beta1.Table{
+		APIVersion: ptr.To(storagev1beta1.TableAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.TableKindTable),
+		Metadata:   externalName(t.Name),
+		Spec: &storagev1beta1.TableSpec{
+			ForProvider: &storagev1beta1.TableSpecForProvider{
+				StorageAccountNameSelector: &storagev1beta1.TableSpecForProviderStorageAccountNameSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}
+}
+
+// Share returns the composed Share for the bucket file share.
+func (s bucketShare) Share() *storagev1beta1.Share {
+	return &storagev1beta1.Share{
+		APIVersion: ptr.To(storagev1beta1.ShareAPIVersionstorageAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(storagev1beta1.ShareKindShare),
+		Metadata:   externalName(s.Name),
+		Spec: &storagev1beta1.ShareSpec{
+			ForProvider: &storagev1beta1.ShareSpecForProvider{
+				Quota:      ptr.To(float64(s.Quota)),
+				AccessTier: ptr.To(s.AccessTier),
+				Metadata:   s.Metadata,
+				StorageAccountNameSelector: &storagev1beta1.ShareSpecForProviderStorageAccountNameSelector{
+					MatchControllerRef: ptr.To(true),
+				},
+			},
+		},
+	}
+}
+
+// externalName returns object metadata that names a composed resource's
+// external resource.
+func externalName(name string) *metav1.ObjectMeta {
+	return &metav1.ObjectMeta{
+		Annotations: &map[string]string{
+			meta.AnnotationKeyExternalName: name,
+		},
+	}
+}
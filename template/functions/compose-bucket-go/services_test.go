@@ -0,0 +1,257 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+var (
+	standardSKU = sku{
+		Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+		Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+		Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+	}
+	premiumSKU = sku{
+		Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+		Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+		Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+	}
+)
+
+func TestQueuesFrom(t *testing.T) {
+	type want struct {
+		queues []bucketQueue
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		sku    sku
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no queues are requested, no queues should be returned.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			sku:    premiumSKU,
+			want:   want{},
+		},
+		"Queues": {
+			reason: "Each queue should be composed as a resource named after the queue.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Queues: &[]v1alpha1.XStorageBucketSpecParametersQueuesItem{
+					{Name: ptr.To("jobs"), Metadata: &map[string]string{"team": "a"}},
+					{Name: ptr.To("dead-letters")},
+				},
+			},
+			sku: standardSKU,
+			want: want{
+				queues: []bucketQueue{
+					{ResourceName: "queue-jobs", Name: "jobs", Metadata: &map[string]string{"team": "a"}},
+					{ResourceName: "queue-dead-letters", Name: "dead-letters"},
+				},
+			},
+		},
+		"UnsupportedSKU": {
+			reason: "Queues should be rejected unless the storage account is a Standard StorageV2 account.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Queues: &[]v1alpha1.XStorageBucketSpecParametersQueuesItem{{Name: ptr.To("jobs")}},
+			},
+			sku: premiumSKU,
+			want: want{
+				err: errors.New("Premium BlockBlobStorage storage accounts don't support queues; use a Standard StorageV2 storage account"),
+			},
+		},
+		"InvalidName": {
+			reason: "Queue names with consecutive hyphens should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Queues: &[]v1alpha1.XStorageBucketSpecParametersQueuesItem{{Name: ptr.To("dead--letters")}},
+			},
+			sku: standardSKU,
+			want: want{
+				err: errors.New(`invalid queue name "dead--letters": must be 3-63 lowercase alphanumeric characters or single hyphens, and must start and end with a letter or number`),
+			},
+		},
+		"DuplicateName": {
+			reason: "Queues that duplicate an earlier queue should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Queues: &[]v1alpha1.XStorageBucketSpecParametersQueuesItem{{Name: ptr.To("jobs")}, {Name: ptr.To("jobs")}},
+			},
+			sku: standardSKU,
+			want: want{
+				err: errors.New(`duplicate queue name "jobs"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := queuesFrom(tc.params, tc.sku)
+
+			if diff := cmp.Diff(tc.want.queues, got); diff != "" {
+				t.Errorf("%s\nqueuesFrom(...): -want queues, +got queues:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nqueuesFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTablesFrom(t *testing.T) {
+	type want struct {
+		tables []bucketTable
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no tables are requested, no tables should be returned.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			want:   want{},
+		},
+		"Tables": {
+			reason: "Each table should be composed as a resource named after the table.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tables: &[]v1alpha1.XStorageBucketSpecParametersTablesItem{{Name: ptr.To("Orders")}},
+			},
+			want: want{
+				tables: []bucketTable{
+					{ResourceName: "table-Orders", Name: "Orders"},
+				},
+			},
+		},
+		"InvalidName": {
+			reason: "Table names that don't start with a letter should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tables: &[]v1alpha1.XStorageBucketSpecParametersTablesItem{{Name: ptr.To("2024orders")}},
+			},
+			want: want{
+				err: errors.New(`invalid table name "2024orders": must be 3-63 alphanumeric characters, and must start with a letter`),
+			},
+		},
+		"DuplicateName": {
+			reason: "Tables that differ from an earlier table only by case should be rejected, because table names are case-insensitive.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tables: &[]v1alpha1.XStorageBucketSpecParametersTablesItem{{Name: ptr.To("Orders")}, {Name: ptr.To("orders")}},
+			},
+			want: want{
+				err: errors.New(`duplicate table name "orders"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tablesFrom(tc.params, standardSKU)
+
+			if diff := cmp.Diff(tc.want.tables, got); diff != "" {
+				t.Errorf("%s\ntablesFrom(...): -want tables, +got tables:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ntablesFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSharesFrom(t *testing.T) {
+	type want struct {
+		shares []bucketShare
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no file shares are requested, no file shares should be returned.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			want:   want{},
+		},
+		"Shares": {
+			reason: "File shares should use the TransactionOptimized access tier unless the XR requests another tier.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				FileShares: &[]v1alpha1.XStorageBucketSpecParametersFileSharesItem{
+					{Name: ptr.To("home"), Quota: ptr.To(100)},
+					{Name: ptr.To("archive"), Quota: ptr.To(5120), AccessTier: ptr.To(v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTierCool)},
+				},
+			},
+			want: want{
+				shares: []bucketShare{
+					{ResourceName: "share-home", Name: "home", Quota: 100, AccessTier: "TransactionOptimized"},
+					{ResourceName: "share-archive", Name: "archive", Quota: 5120, AccessTier: "Cool"},
+				},
+			},
+		},
+		"QuotaTooLarge": {
+			reason: "File shares larger than 5 TiB should be rejected, because they require large file shares.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				FileShares: &[]v1alpha1.XStorageBucketSpecParametersFileSharesItem{{Name: ptr.To("home"), Quota: ptr.To(5121)}},
+			},
+			want: want{
+				err: errors.New(`invalid quota of file share "home": must be between 1 and 5120 GiB`),
+			},
+		},
+		"MissingQuota": {
+			reason: "File shares without a quota should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				FileShares: &[]v1alpha1.XStorageBucketSpecParametersFileSharesItem{{Name: ptr.To("home")}},
+			},
+			want: want{
+				err: errors.New(`invalid quota of file share "home": must be between 1 and 5120 GiB`),
+			},
+		},
+		"UnsupportedAccessTier": {
+			reason: "Access tiers Azure Files doesn't support should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				FileShares: &[]v1alpha1.XStorageBucketSpecParametersFileSharesItem{
+					{Name: ptr.To("home"), Quota: ptr.To(100), AccessTier: ptr.To(v1alpha1.XStorageBucketSpecParametersFileSharesItemAccessTier("Premium"))},
+				},
+			},
+			want: want{
+				err: errors.New(`invalid access tier of file share "home": unsupported tier "Premium"; use one of TransactionOptimized, Hot, Cool`),
+			},
+		},
+		"DuplicateName": {
+			reason: "File shares that duplicate an earlier share should be rejected.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				FileShares: &[]v1alpha1.XStorageBucketSpecParametersFileSharesItem{
+					{Name: ptr.To("home"), Quota: ptr.To(100)},
+					{Name: ptr.To("home"), Quota: ptr.To(200)},
+				},
+			},
+			want: want{
+				err: errors.New(`duplicate file share name "home"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := sharesFrom(tc.params, standardSKU)
+
+			if diff := cmp.Diff(tc.want.shares, got); diff != "" {
+				t.Errorf("%s\nsharesFrom(...): -want shares, +got shares:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nsharesFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
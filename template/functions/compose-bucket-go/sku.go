@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Defaults for the storage account's SKU, matching those in the XRD.
+const (
+	defaultTier        = v1alpha1.XStorageBucketSpecParametersTierStandard
+	defaultReplication = v1alpha1.XStorageBucketSpecParametersReplicationLRS
+	defaultKind        = v1alpha1.XStorageBucketSpecParametersKindStorageV2
+)
+
+// An sku is the performance tier, replication type and kind of a storage
+// account.
+type sku struct {
+	Tier        v1alpha1.XStorageBucketSpecParametersTier
+	Replication v1alpha1.XStorageBucketSpecParametersReplication
+	Kind        v1alpha1.XStorageBucketSpecParametersKind
+}
+
+// skuFrom returns the SKU requested by the supplied parameters, falling back
+// to the defaults for anything that isn't set.
+func skuFrom(params *v1alpha1.XStorageBucketSpecParameters) sku {
+	return sku{
+		Tier:        ptr.Deref(params.Tier, defaultTier),
+		Replication: ptr.Deref(params.Replication, defaultReplication),
+		Kind:        ptr.Deref(params.Kind, defaultKind),
+	}
+}
+
+// validate returns an error if Azure doesn't support the SKU's combination of
+// tier, replication type and kind.
+func (s sku) validate() error {
+	switch s.Tier {
+	case v1alpha1.XStorageBucketSpecParametersTierPremium:
+		if s.Kind == v1alpha1.XStorageBucketSpecParametersKindBlobStorage {
+			return errors.Errorf("%s storage accounts don't support the %s tier; use the %s tier", s.Kind, s.Tier, v1alpha1.XStorageBucketSpecParametersTierStandard)
+		}
+		return s.validateReplication(v1alpha1.XStorageBucketSpecParametersReplicationLRS, v1alpha1.XStorageBucketSpecParametersReplicationZRS)
+	case v1alpha1.XStorageBucketSpecParametersTierStandard:
+		switch s.Kind {
+		case v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage:
+			return errors.Errorf("%s storage accounts don't support the %s tier; use the %s tier", s.Kind, s.Tier, v1alpha1.XStorageBucketSpecParametersTierPremium)
+		case v1alpha1.XStorageBucketSpecParametersKindBlobStorage:
+			return s.validateReplication(v1alpha1.XStorageBucketSpecParametersReplicationLRS, v1alpha1.XStorageBucketSpecParametersReplicationGRS, v1alpha1.XStorageBucketSpecParametersReplicationRAGRS)
+		}
+	}
+	return nil
+}
+
+func (s sku) validateReplication(supported ...v1alpha1.XStorageBucketSpecParametersReplication) error {
+	if slices.Contains(supported, s.Replication) {
+		return nil
+	}
+	names := make([]string, len(supported))
+	for i, r := range supported {
+		names[i] = string(r)
+	}
+	return errors.Errorf("%s %s storage accounts don't support %s replication; use one of %s", s.Tier, s.Kind, s.Replication, strings.Join(names, ", "))
+}
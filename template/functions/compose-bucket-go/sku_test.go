@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestSKU(t *testing.T) {
+	type want struct {
+		sku sku
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params *v1alpha1.XStorageBucketSpecParameters
+		want   want
+	}{
+		"Defaults": {
+			reason: "Unset parameters should fall back to a Standard, LRS, StorageV2 account.",
+			params: &v1alpha1.XStorageBucketSpecParameters{},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+				},
+			},
+		},
+		"StandardGeoZoneRedundant": {
+			reason: "Standard StorageV2 accounts should support RAGZRS replication.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Replication: ptr.To(v1alpha1.XStorageBucketSpecParametersReplicationRAGZRS),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationRAGZRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+				},
+			},
+		},
+		"PremiumBlockBlob": {
+			reason: "Premium BlockBlobStorage accounts should support ZRS replication.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tier:        ptr.To(v1alpha1.XStorageBucketSpecParametersTierPremium),
+				Replication: ptr.To(v1alpha1.XStorageBucketSpecParametersReplicationZRS),
+				Kind:        ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationZRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+				},
+			},
+		},
+		"PremiumGeoRedundant": {
+			reason: "Premium accounts should not support geo-redundant replication.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tier:        ptr.To(v1alpha1.XStorageBucketSpecParametersTierPremium),
+				Replication: ptr.To(v1alpha1.XStorageBucketSpecParametersReplicationGRS),
+				Kind:        ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationGRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+				},
+				err: errors.New("Premium BlockBlobStorage storage accounts don't support GRS replication; use one of LRS, ZRS"),
+			},
+		},
+		"PremiumBlobStorage": {
+			reason: "BlobStorage accounts should not support the Premium tier.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Tier: ptr.To(v1alpha1.XStorageBucketSpecParametersTierPremium),
+				Kind: ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlobStorage),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlobStorage,
+				},
+				err: errors.New("BlobStorage storage accounts don't support the Premium tier; use the Standard tier"),
+			},
+		},
+		"StandardBlockBlob": {
+			reason: "BlockBlobStorage accounts should not support the Standard tier.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Kind: ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+				},
+				err: errors.New("BlockBlobStorage storage accounts don't support the Standard tier; use the Premium tier"),
+			},
+		},
+		"StandardBlobStorageZoneRedundant": {
+			reason: "BlobStorage accounts should not support zone-redundant replication.",
+			params: &v1alpha1.XStorageBucketSpecParameters{
+				Replication: ptr.To(v1alpha1.XStorageBucketSpecParametersReplicationGZRS),
+				Kind:        ptr.To(v1alpha1.XStorageBucketSpecParametersKindBlobStorage),
+			},
+			want: want{
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationGZRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlobStorage,
+				},
+				err: errors.New("Standard BlobStorage storage accounts don't support GZRS replication; use one of LRS, GRS, RAGRS"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := skuFrom(tc.params)
+			err := s.validate()
+
+			if diff := cmp.Diff(tc.want.sku, s); diff != "" {
+				t.Errorf("%s\nskuFrom(...): -want sku, +got sku:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ns.validate(): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// Keys of the tags we set on every taggable composed resource, so that Azure
+// cost reports can attribute resources to the XR and claim they belong to.
+const (
+	tagKeyComposite      = "crossplane-composite"
+	tagKeyClaimName      = "crossplane-claim-name"
+	tagKeyClaimNamespace = "crossplane-claim-namespace"
+	tagKeyManagedBy      = "managed-by"
+
+	tagValueManagedBy = "crossplane"
+)
+
+// Labels Crossplane sets on XRs that are bound to a claim.
+const (
+	labelClaimName      = "crossplane.io/claim-name"
+	labelClaimNamespace = "crossplane.io/claim-namespace"
+)
+
+// Azure limits the tags of storage accounts more tightly than those of other
+// resources, so we apply its limits to every composed resource.
+const (
+	tagsMaxCount       = 50
+	tagKeyMaxLength    = 128
+	tagValueMaxLength  = 256
+	tagKeyInvalidChars = `<>%&\?/`
+)
+
+// tagsFrom returns the tags of the supplied XR's composed resources: the XR's
+// tags merged with tags identifying the XR, its claim and Crossplane. The XR's
+// tags must include the supplied required tags, such as owner and cost-center,
+// which the platform requires for cost allocation.
+func tagsFrom(xr *v1alpha1.XStorageBucket, required []string) (map[string]string, error) {
+	params := xr.Spec.Parameters
+
+	tags := make(map[string]string)
+	for _, k := range slices.Sorted(maps.Keys(ptr.Deref(params.Tags, nil))) {
+		v := (*params.Tags)[k]
+		if err := validateTag(k, v); err != nil {
+			return nil, errors.Wrapf(err, "invalid tag %q", k)
+		}
+		tags[k] = v
+	}
+
+	for _, k := range required {
+		if strings.TrimSpace(tags[k]) == "" {
+			return nil, errors.Errorf("missing required tag %q", k)
+		}
+	}
+
+	var name string
+	var labels map[string]string
+	if xr.Metadata != nil {
+		name = ptr.Deref(xr.Metadata.Name, "")
+		labels = ptr.Deref(xr.Metadata.Labels, nil)
+	}
+	auto := map[string]string{
+		tagKeyComposite:      name,
+		tagKeyClaimName:      labels[labelClaimName],
+		tagKeyClaimNamespace: labels[labelClaimNamespace],
+		tagKeyManagedBy:      tagValueManagedBy,
+	}
+	for k, v := range auto {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+
+	if len(tags) > tagsMaxCount {
+		return nil, errors.Errorf("too many tags: Azure allows at most %d, including those set automatically", tagsMaxCount)
+	}
+
+	return tags, nil
+}
+
+// validateTag returns an error if the supplied tag can't be set by an XR.
+func validateTag(key, value string) error {
+	switch {
+	case strings.TrimSpace(key) == "":
+		return errors.New("key must not be blank")
+	case len(key) > tagKeyMaxLength:
+		return errors.Errorf("key must be at most %d characters", tagKeyMaxLength)
+	case strings.ContainsAny(key, tagKeyInvalidChars):
+		return errors.Errorf("key must not contain any of %s", tagKeyInvalidChars)
+	case len(value) > tagValueMaxLength:
+		return errors.Errorf("value must be at most %d characters", tagValueMaxLength)
+	}
+
+	// Azure tag keys are case-insensitive.
+	for _, reserved := range []string{tagKeyComposite, tagKeyClaimName, tagKeyClaimNamespace, tagKeyManagedBy} {
+		if strings.EqualFold(key, reserved) {
+			return errors.New("key is reserved for tags set automatically")
+		}
+	}
+	return nil
+}
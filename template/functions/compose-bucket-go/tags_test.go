@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	metav1 "dev.upbound.io/models/io/k8s/meta/v1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestTagsFrom(t *testing.T) {
+	xr := func(params *v1alpha1.XStorageBucketSpecParameters, labels map[string]string) *v1alpha1.XStorageBucket {
+		return &v1alpha1.XStorageBucket{
+			Metadata: &metav1.ObjectMeta{
+				Name:   ptr.To("example-xr"),
+				Labels: &labels,
+			},
+			Spec: &v1alpha1.XStorageBucketSpec{Parameters: params},
+		}
+	}
+
+	tooMany := make(map[string]string)
+	for i := range tagsMaxCount - 1 {
+		tooMany[fmt.Sprintf("tag-%d", i)] = "value"
+	}
+
+	type want struct {
+		tags map[string]string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		xr       *v1alpha1.XStorageBucket
+		required []string
+		want     want
+	}{
+		"NotRequested": {
+			reason: "If no tags are requested, only the tags identifying the XR and Crossplane should be returned.",
+			xr:     xr(&v1alpha1.XStorageBucketSpecParameters{}, nil),
+			want: want{
+				tags: map[string]string{
+					"crossplane-composite": "example-xr",
+					"managed-by":           "crossplane",
+				},
+			},
+		},
+		"Claimed": {
+			reason: "The requested tags should be merged with the tags identifying the XR, its claim and Crossplane.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &map[string]string{
+					"environment": "production",
+				},
+			}, map[string]string{
+				"crossplane.io/claim-name":      "example",
+				"crossplane.io/claim-namespace": "team-a",
+			}),
+			want: want{
+				tags: map[string]string{
+					"environment":                "production",
+					"crossplane-composite":       "example-xr",
+					"crossplane-claim-name":      "example",
+					"crossplane-claim-namespace": "team-a",
+					"managed-by":                 "crossplane",
+				},
+			},
+		},
+		"RequiredTags": {
+			reason: "XRs should be accepted if they set the required tags.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &map[string]string{
+					"owner":       "team-a@example.com",
+					"cost-center": "cc-1234",
+				},
+			}, nil),
+			required: []string{"owner", "cost-center"},
+			want: want{
+				tags: map[string]string{
+					"owner":                "team-a@example.com",
+					"cost-center":          "cc-1234",
+					"crossplane-composite": "example-xr",
+					"managed-by":           "crossplane",
+				},
+			},
+		},
+		"RequiredTagsMissing": {
+			reason: "XRs should be rejected if they don't set the required tags.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &map[string]string{
+					"owner":       "team-a@example.com",
+					"cost-center": " ",
+				},
+			}, nil),
+			required: []string{"owner", "cost-center"},
+			want: want{
+				err: errors.New(`missing required tag "cost-center"`),
+			},
+		},
+		"ReservedKey": {
+			reason: "Tags that would override the tags set automatically should be rejected, regardless of case.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &map[string]string{
+					"Managed-By": "terraform",
+				},
+			}, nil),
+			want: want{
+				err: errors.Wrap(errors.New("key is reserved for tags set automatically"), `invalid tag "Managed-By"`),
+			},
+		},
+		"InvalidKey": {
+			reason: "Tags with keys Azure doesn't accept should be rejected.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &map[string]string{
+					"team/owner": "team-a",
+				},
+			}, nil),
+			want: want{
+				err: errors.Wrap(errors.New(`key must not contain any of <>%&\?/`), `invalid tag "team/owner"`),
+			},
+		},
+		"TooMany": {
+			reason: "Tags should be rejected if, together with the tags set automatically, they exceed Azure's limit.",
+			xr: xr(&v1alpha1.XStorageBucketSpecParameters{
+				Tags: &tooMany,
+			}, nil),
+			want: want{
+				err: errors.New("too many tags: Azure allows at most 50, including those set automatically"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tagsFrom(tc.xr, tc.required)
+
+			if diff := cmp.Diff(tc.want.tags, got); diff != "" {
+				t.Errorf("%s\ntagsFrom(...): -want tags, +got tags:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\ntagsFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
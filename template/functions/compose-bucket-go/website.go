@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// defaultIndexDocument is served for requests to a static website's root, and
+// to any of its directories.
+const defaultIndexDocument = "index.html"
+
+// staticWebsite returns the storage account's static website configuration,
+// or nil if the supplied parameters don't request a static website. Azure
+// serves static websites from a $web container it creates itself.
+func staticWebsite(params *v1alpha1.XStorageBucketSpecParameters, s sku) (*[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem, error) {
+	w := params.Website
+	if w == nil {
+		return nil, nil
+	}
+
+	// Premium StorageV2 accounts only store page blobs, so they can't serve
+	// static websites either.
+	standardV2 := s.Tier == v1alpha1.XStorageBucketSpecParametersTierStandard && s.Kind == v1alpha1.XStorageBucketSpecParametersKindStorageV2
+	premiumBlockBlob := s.Tier == v1alpha1.XStorageBucketSpecParametersTierPremium && s.Kind == v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage
+	if !standardV2 && !premiumBlockBlob {
+		return nil, errors.Errorf("%s %s storage accounts don't support static websites; use a Standard StorageV2 or Premium BlockBlobStorage account", s.Tier, s.Kind)
+	}
+
+	index := ptr.Deref(w.IndexDocument, defaultIndexDocument)
+	if err := validateWebsiteDocument(index); err != nil {
+		return nil, errors.Wrapf(err, "invalid index document %q", index)
+	}
+	website := storagev1beta1.AccountSpecForProviderStaticWebsiteItem{
+		IndexDocument: ptr.To(index),
+	}
+
+	if w.Error404Document != nil {
+		if err := validateWebsiteDocument(*w.Error404Document); err != nil {
+			return nil, errors.Wrapf(err, "invalid 404 document %q", *w.Error404Document)
+		}
+		website.Error404Document = w.Error404Document
+	}
+
+	return &[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem{website}, nil
+}
+
+// validateWebsiteDocument returns an error if the supplied static website
+// document isn't the name of a blob in the $web container.
+func validateWebsiteDocument(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("must not be blank")
+	case strings.HasPrefix(name, "/"):
+		return errors.New("must be relative to the $web container")
+	}
+	return nil
+}
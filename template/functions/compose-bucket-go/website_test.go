@@ -0,0 +1,155 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	storagev1beta1 "dev.upbound.io/models/io/upbound/azure/storage/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestStaticWebsite(t *testing.T) {
+	storageV2 := sku{
+		Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+		Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+		Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+	}
+
+	type args struct {
+		params *v1alpha1.XStorageBucketSpecParameters
+		sku    sku
+	}
+	type want struct {
+		website *[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no static website is requested, no static website should be configured.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{},
+				sku:    storageV2,
+			},
+			want: want{},
+		},
+		"Defaults": {
+			reason: "Static websites should serve index.html by default, and Azure's own 404 page unless a 404 document is supplied.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				sku: storageV2,
+			},
+			want: want{
+				website: &[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem{{
+					IndexDocument: ptr.To("index.html"),
+				}},
+			},
+		},
+		"Documents": {
+			reason: "Static websites should serve the supplied documents.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{
+						IndexDocument:    ptr.To("default.htm"),
+						Error404Document: ptr.To("errors/404.html"),
+					},
+				},
+				sku: storageV2,
+			},
+			want: want{
+				website: &[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem{{
+					IndexDocument:    ptr.To("default.htm"),
+					Error404Document: ptr.To("errors/404.html"),
+				}},
+			},
+		},
+		"BlobStorage": {
+			reason: "Static websites should be rejected for BlobStorage accounts.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierStandard,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlobStorage,
+				},
+			},
+			want: want{
+				err: errors.New("Standard BlobStorage storage accounts don't support static websites; use a Standard StorageV2 or Premium BlockBlobStorage account"),
+			},
+		},
+		"PremiumStorageV2": {
+			reason: "Static websites should be rejected for Premium StorageV2 accounts, which only store page blobs.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationLRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindStorageV2,
+				},
+			},
+			want: want{
+				err: errors.New("Premium StorageV2 storage accounts don't support static websites; use a Standard StorageV2 or Premium BlockBlobStorage account"),
+			},
+		},
+		"PremiumBlockBlobStorage": {
+			reason: "Static websites should be supported by Premium BlockBlobStorage accounts.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{},
+				},
+				sku: sku{
+					Tier:        v1alpha1.XStorageBucketSpecParametersTierPremium,
+					Replication: v1alpha1.XStorageBucketSpecParametersReplicationZRS,
+					Kind:        v1alpha1.XStorageBucketSpecParametersKindBlockBlobStorage,
+				},
+			},
+			want: want{
+				website: &[]storagev1beta1.AccountSpecForProviderStaticWebsiteItem{{
+					IndexDocument: ptr.To("index.html"),
+				}},
+			},
+		},
+		"Absolute404Document": {
+			reason: "404 documents outside the $web container should be rejected.",
+			args: args{
+				params: &v1alpha1.XStorageBucketSpecParameters{
+					Website: &v1alpha1.XStorageBucketSpecParametersWebsite{
+						Error404Document: ptr.To("/404.html"),
+					},
+				},
+				sku: storageV2,
+			},
+			want: want{
+				err: errors.Wrap(errors.New("must be relative to the $web container"), `invalid 404 document "/404.html"`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := staticWebsite(tc.args.params, tc.args.sku)
+
+			if diff := cmp.Diff(tc.want.website, got); diff != "" {
+				t.Errorf("%s\nstaticWebsite(...): -want website, +got website:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nstaticWebsite(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
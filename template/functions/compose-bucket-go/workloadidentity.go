@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	managedidentityv1beta1 "dev.upbound.io/models/io/upbound/azure/managedidentity/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// workloadIdentityCredentialResourceName is the name of the composed
+// federated identity credential that lets a Kubernetes service account act as
+// the bucket's identity.
+const workloadIdentityCredentialResourceName resource.Name = "workload-identity-credential"
+
+// workloadIdentityAudience is the audience of the service account tokens
+// Entra ID exchanges for access tokens.
+const workloadIdentityAudience = "api://AzureADTokenExchange"
+
+var (
+	// namespacePattern matches a Kubernetes namespace name.
+	namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+	// serviceAccountNamePattern matches a Kubernetes service account name.
+	serviceAccountNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+)
+
+// A workloadIdentity is a Kubernetes service account federated with the
+// bucket's identity.
+type workloadIdentity struct {
+	// Issuer is the URL of the cluster's OIDC issuer.
+	Issuer string
+
+	// Subject of the service account's tokens.
+	Subject string
+}
+
+// workloadIdentityFrom returns the workload identity requested by the
+// supplied parameters, or nil if no workload identity is requested.
+func workloadIdentityFrom(wi *v1alpha1.XStorageBucketSpecParametersWorkloadIdentity) (*workloadIdentity, error) {
+	if wi == nil {
+		return nil, nil
+	}
+
+	issuer := ptr.Deref(wi.IssuerURL, "")
+	u, err := url.Parse(issuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.Errorf("invalid issuer URL %q: must be an https URL without a query or fragment", issuer)
+	}
+
+	ns := ptr.Deref(wi.Namespace, "")
+	if !namespacePattern.MatchString(ns) {
+		return nil, errors.Errorf("invalid namespace %q: must be a Kubernetes namespace name", ns)
+	}
+	sa := ptr.Deref(wi.ServiceAccountName, "")
+	if !serviceAccountNamePattern.MatchString(sa) {
+		return nil, errors.Errorf("invalid service account name %q: must be a Kubernetes service account name", sa)
+	}
+
+	return &workloadIdentity{
+		Issuer:  issuer,
+		Subject: "system:serviceaccount:" + ns + ":" + sa,
+	}, nil
+}
+
+// FederatedIdentityCredential returns the composed federated identity
+// credential that lets the service account act as the supplied identity.
+func (w workloadIdentity) FederatedIdentityCredential(identityID string, group bucketResourceGroup) *managedidentityv1beta1.FederatedIdentityCredential {
+	fic := &managedidentityv1beta1.FederatedIdentityCredential{
+		APIVersion: ptr.To(managedidentityv1beta1.FederatedIdentityCredentialAPIVersionmanagedidentityAzureUpboundIoV1Beta1),
+		Kind:       ptr.To(managedidentityv1beta1.FederatedIdentityCredentialKindFederatedIdentityCredential),
+		Spec: &managedidentityv1beta1.FederatedIdentityCredentialSpec{
+			ForProvider: &managedidentityv1beta1.FederatedIdentityCredentialSpecForProvider{
+				Audience: &[]string{workloadIdentityAudience},
+				Issuer:   ptr.To(w.Issuer),
+				Subject:  ptr.To(w.Subject),
+				ParentID: ptr.To(identityID),
+			},
+		},
+	}
+	if group.Name != "" {
+		fic.Spec.ForProvider.ResourceGroupName = ptr.To(group.Name)
+	} else {
+		fic.Spec.ForProvider.ResourceGroupNameSelector = &managedidentityv1beta1.FederatedIdentityCredentialSpecForProviderResourceGroupNameSelector{
+			MatchControllerRef: ptr.To(true),
+		}
+	}
+	return fic
+}
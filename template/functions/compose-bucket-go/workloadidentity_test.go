@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"dev.upbound.io/models/com/example/platform/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+func TestWorkloadIdentityFrom(t *testing.T) {
+	issuer := "https://eastus.oic.prod-aks.azure.com/00000000-0000-0000-0000-000000000000/11111111-1111-1111-1111-111111111111/"
+
+	type want struct {
+		wi  *workloadIdentity
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		wi     *v1alpha1.XStorageBucketSpecParametersWorkloadIdentity
+		want   want
+	}{
+		"NotRequested": {
+			reason: "If no workload identity is requested, no workload identity should be returned.",
+			want:   want{},
+		},
+		"ServiceAccount": {
+			reason: "The workload identity's subject should be the service account.",
+			wi: &v1alpha1.XStorageBucketSpecParametersWorkloadIdentity{
+				IssuerURL:          ptr.To(issuer),
+				Namespace:          ptr.To("team-a"),
+				ServiceAccountName: ptr.To("uploader"),
+			},
+			want: want{
+				wi: &workloadIdentity{
+					Issuer:  issuer,
+					Subject: "system:serviceaccount:team-a:uploader",
+				},
+			},
+		},
+		"InsecureIssuer": {
+			reason: "Issuers that aren't served over https should be rejected.",
+			wi: &v1alpha1.XStorageBucketSpecParametersWorkloadIdentity{
+				IssuerURL:          ptr.To("http://issuer.example.com"),
+				Namespace:          ptr.To("team-a"),
+				ServiceAccountName: ptr.To("uploader"),
+			},
+			want: want{
+				err: errors.New(`invalid issuer URL "http://issuer.example.com": must be an https URL without a query or fragment`),
+			},
+		},
+		"InvalidNamespace": {
+			reason: "Namespaces Kubernetes doesn't accept should be rejected.",
+			wi: &v1alpha1.XStorageBucketSpecParametersWorkloadIdentity{
+				IssuerURL:          ptr.To(issuer),
+				Namespace:          ptr.To("Team_A"),
+				ServiceAccountName: ptr.To("uploader"),
+			},
+			want: want{
+				err: errors.New(`invalid namespace "Team_A": must be a Kubernetes namespace name`),
+			},
+		},
+		"MissingServiceAccount": {
+			reason: "Workload identities without a service account should be rejected.",
+			wi: &v1alpha1.XStorageBucketSpecParametersWorkloadIdentity{
+				IssuerURL: ptr.To(issuer),
+				Namespace: ptr.To("team-a"),
+			},
+			want: want{
+				err: errors.New(`invalid service account name "": must be a Kubernetes service account name`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := workloadIdentityFrom(tc.wi)
+
+			if diff := cmp.Diff(tc.want.wi, got); diff != "" {
+				t.Errorf("%s\nworkloadIdentityFrom(...): -want workload identity, +got workload identity:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("%s\nworkloadIdentityFrom(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
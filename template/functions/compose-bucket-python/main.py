@@ -1,4 +1,7 @@
-from crossplane.function import resource
+import hashlib
+import re
+
+from crossplane.function import resource, response
 from crossplane.function.proto.v1 import run_function_pb2 as fnv1
 
 from .model.io.k8s.apimachinery.pkg.apis.meta import v1 as metav1
@@ -7,27 +10,239 @@ from .model.io.upbound.azure.storage.account import v1beta1 as acctv1beta1
 from .model.io.upbound.azure.storage.container import v1beta1 as contv1beta1
 from .model.com.example.platform.xstoragebucket import v1alpha1
 
+# Storage account names must be 3-24 character, lowercase alphanumeric strings
+# that are globally unique within Azure.
+ACCOUNT_NAME_MIN_LENGTH = 3
+ACCOUNT_NAME_MAX_LENGTH = 24
+
+# The number of hex characters of the XR's UID hash appended to the account
+# name to make it globally unique.
+ACCOUNT_NAME_HASH_LENGTH = 8
+
+
+class InvalidAccountNameError(ValueError):
+    """Raised when no valid storage account name can be derived for an XR."""
+
+    def __init__(self, name: str, reason: str):
+        super().__init__(f'cannot derive a storage account name from "{name}": {reason}')
+        self.name = name
+        self.reason = reason
+
+
+def derive_account_name(name: str, uid: str | None) -> str:
+    """Derive a storage account name from an XR's name and UID.
+
+    The name is lowercased and stripped of anything that isn't alphanumeric.
+    When a UID is supplied the name is truncated and suffixed with a hash of the
+    UID, which keeps it unique even when XR names collide or are truncated.
+    Without a UID the normalized name is only truncated, and must be long enough
+    on its own.
+
+    The compose-bucket-go and compose-bucket-go-templating functions derive the
+    same names, and must be kept in sync with this one.
+    """
+    base = re.sub("[^a-z0-9]", "", name.lower())
+
+    if not uid:
+        if len(base) < ACCOUNT_NAME_MIN_LENGTH:
+            raise InvalidAccountNameError(
+                name,
+                f"must contain at least {ACCOUNT_NAME_MIN_LENGTH} alphanumeric characters",
+            )
+        return base[:ACCOUNT_NAME_MAX_LENGTH]
+
+    digest = hashlib.sha256(uid.encode()).hexdigest()[:ACCOUNT_NAME_HASH_LENGTH]
+    return base[: ACCOUNT_NAME_MAX_LENGTH - ACCOUNT_NAME_HASH_LENGTH] + digest
+
+
+# The access types of the bucket's container, by ACL. "public" is a deprecated
+# alias of "blob".
+CONTAINER_ACCESS_TYPES = {
+    "private": "private",
+    "blob": "blob",
+    "container": "container",
+}
+DEPRECATED_ACLS = {
+    "public": "blob",
+}
+
+# The ACL of buckets that don't request one.
+DEFAULT_ACL = "private"
+
+
+class UnsupportedACLError(ValueError):
+    """Raised when an XR requests an ACL that isn't supported."""
+
+    def __init__(self, acl: str):
+        super().__init__(
+            f'unsupported ACL "{acl}"; use one of {", ".join(CONTAINER_ACCESS_TYPES)}'
+        )
+        self.acl = acl
+
+
+def container_access_type(acl: str | None) -> tuple[str, str]:
+    """Return the container access type for an ACL, and a notice if it's deprecated.
+
+    The XRD validates ACLs, but XRs created before it did may still request
+    others, which are rejected rather than silently made private.
+
+    The compose-bucket-go and compose-bucket-go-templating functions handle ACLs
+    in the same way, and must be kept in sync with this one.
+    """
+    acl = acl or DEFAULT_ACL
+    if acl in CONTAINER_ACCESS_TYPES:
+        return CONTAINER_ACCESS_TYPES[acl], ""
+    if acl in DEPRECATED_ACLS:
+        replacement = DEPRECATED_ACLS[acl]
+        return replacement, f'acl "{acl}" is deprecated; use "{replacement}" instead'
+    raise UnsupportedACLError(acl)
+
+
+# Parameters only the compose-bucket-go function supports. XRs that set them
+# are rejected rather than composed without them, which would silently leave a
+# bucket without the network isolation, encryption or data protection it asks
+# for. The API server defaults some of them on every XR, so those are only
+# rejected if they differ from their defaults.
+UNSUPPORTED_PARAMETERS = [
+    "access",
+    "containers",
+    "cors",
+    "dataProtection",
+    "diagnostics",
+    "encryption",
+    "fileShares",
+    "immutability",
+    "lifecycle",
+    "network",
+    "privateEndpoint",
+    "providerConfigName",
+    "queues",
+    "resourceGroupName",
+    "tables",
+    "tags",
+    "website",
+    "workloadIdentity",
+]
+DEFAULTED_UNSUPPORTED_PARAMETERS = {
+    "kind": "StorageV2",
+    "replication": "LRS",
+    "tier": "Standard",
+}
+
+
+def unsupported_parameters(params: dict) -> list[str]:
+    """Return the sorted names of the parameters an XR sets that aren't supported.
+
+    The compose-bucket-kcl and compose-bucket-go-templating functions reject the
+    same parameters, and must be kept in sync with this one.
+    """
+    unsupported = [p for p in UNSUPPORTED_PARAMETERS if params.get(p) is not None]
+    unsupported += [
+        p
+        for p, default in DEFAULTED_UNSUPPORTED_PARAMETERS.items()
+        if params.get(p, default) != default
+    ]
+    return sorted(unsupported)
+
+
+class InvalidManagementPoliciesError(ValueError):
+    """Raised when an XR requests management policies Crossplane can't apply."""
+
+
+def resource_policies(
+    deletion_policy: str | None, management_policies: list[str] | None
+) -> tuple[dict, dict]:
+    """Return the policies of the bucket's resources and of its resource group.
+
+    Policies are applied in the same way as by resourcePolicies in the
+    compose-bucket-go function's policies.go, which explains why, and must be
+    kept in sync with it.
+    """
+    policies = {}
+    if deletion_policy:
+        policies["deletionPolicy"] = deletion_policy
+    if management_policies is not None:
+        if "*" in management_policies:
+            if len(management_policies) > 1:
+                raise InvalidManagementPoliciesError(
+                    'management policy "*" must not be combined with other policies'
+                )
+        elif "Observe" not in management_policies:
+            raise InvalidManagementPoliciesError(
+                'management policies must include "Observe"'
+            )
+        policies["managementPolicies"] = management_policies
+
+    orphaned = deletion_policy == "Orphan" or (
+        management_policies is not None
+        and "*" not in management_policies
+        and "Delete" not in management_policies
+    )
+    return policies, {**policies, "deletionPolicy": "Orphan"} if orphaned else policies
+
 
 def compose(req: fnv1.RunFunctionRequest, rsp: fnv1.RunFunctionResponse):
+    # Read the ACL before parsing the XR, so that an unsupported ACL is reported
+    # as such rather than as a model validation error.
+    xr = resource.struct_to_dict(req.observed.composite.resource)
+    raw_params = xr.get("spec", {}).get("parameters", {})
+
+    unsupported = unsupported_parameters(raw_params)
+    if unsupported:
+        response.fatal(
+            rsp,
+            f"unsupported parameters: {', '.join(unsupported)}; "
+            "use the compose-bucket-go function to compose buckets that set them",
+        )
+        return
+
+    try:
+        access_type, notice = container_access_type(raw_params.get("acl"))
+    except UnsupportedACLError as e:
+        response.fatal(rsp, f"invalid acl parameter: {e}")
+        return
+    if notice:
+        response.warning(rsp, notice)
+
+    try:
+        policies, group_policies = resource_policies(
+            raw_params.get("deletionPolicy"), raw_params.get("managementPolicies")
+        )
+    except InvalidManagementPoliciesError as e:
+        response.fatal(rsp, f"invalid managementPolicies parameter: {e}")
+        return
+
     observed_xr = v1alpha1.XStorageBucket(**req.observed.composite.resource)
     params = observed_xr.spec.parameters
 
+    # Storage account names are derived from the XR's name and UID. Once the
+    # account exists we keep using its name, so that changes to how names are
+    # derived never rename an existing account.
+    account_external_name = ""
+    if "account" in req.observed.resources:
+        observed_acct = resource.struct_to_dict(req.observed.resources["account"].resource)
+        account_external_name = observed_acct.get("metadata", {}).get("name", "")
+    if not account_external_name:
+        try:
+            account_external_name = derive_account_name(
+                observed_xr.metadata.name or "",  # type: ignore  # Metadata is an optional field, but it'll always be set.
+                observed_xr.metadata.uid,  # type: ignore
+            )
+        except InvalidAccountNameError as e:
+            response.fatal(rsp, f"invalid storage account name: {e}")
+            return
+
     # Create the resource group
     desired_group = rgv1beta1.ResourceGroup(
         spec=rgv1beta1.Spec(
             forProvider=rgv1beta1.ForProvider(
                 location=params.location,
             ),
+            **group_policies,
         ),
     )
     resource.update(rsp.desired.resources["rg"], desired_group)
 
-    # Storage account names must be 3-24 character, lowercase alphanumeric
-    # strings that are globally unique within Azure. We try to generate a valid
-    # one automatically by deriving it from the XR name, which should always be
-    # alphanumeric, lowercase, and separated by hyphens.
-    account_external_name = observed_xr.metadata.name.replace("-", "")  # type: ignore  # Name is an optional field, but it'll always be set.
-
     # Create the storage account
     desired_acct = acctv1beta1.Account(
         metadata=metav1.ObjectMeta(
@@ -39,6 +254,9 @@ def compose(req: fnv1.RunFunctionRequest, rsp: fnv1.RunFunctionResponse):
                 accountReplicationType="LRS",
                 location=params.location,
                 infrastructureEncryptionEnabled=True,
+                # Azure rejects public containers unless their account allows
+                # nested items to be public.
+                allowNestedItemsToBePublic=access_type != "private",
                 blobProperties=[
                     acctv1beta1.BlobProperty(
                         versioningEnabled=params.versioning,
@@ -48,6 +266,7 @@ def compose(req: fnv1.RunFunctionRequest, rsp: fnv1.RunFunctionResponse):
                     matchControllerRef=True
                 ),
             ),
+            **policies,
         ),
     )
     resource.update(rsp.desired.resources["account"], desired_acct)
@@ -58,11 +277,12 @@ def compose(req: fnv1.RunFunctionRequest, rsp: fnv1.RunFunctionResponse):
         ),
         spec=contv1beta1.Spec(
             forProvider=contv1beta1.ForProvider(
-                containerAccessType="blob" if params.acl == "public" else "private",
+                containerAccessType=access_type,
                 storageAccountNameSelector=contv1beta1.StorageAccountNameSelector(
                     matchControllerRef=True
                 ),
             ),
+            **policies,
         ),
     )
     resource.update(rsp.desired.resources["container"], desired_cont)
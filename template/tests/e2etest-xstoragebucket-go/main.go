@@ -43,7 +43,10 @@ func main() {
 				Parameters: &v1alpha1.XStorageBucketSpecParameters{
 					Location:   ptr.To("eastus"),
 					Versioning: ptr.To(true),
-					ACL:        ptr.To("private"),
+					ACL:        ptr.To(v1alpha1.XStorageBucketSpecParametersACLPrivate),
+					// Name the ProviderConfig created below explicitly, so
+					// that the function checks it exists.
+					ProviderConfigName: ptr.To("default"),
 				},
 			},
 		},
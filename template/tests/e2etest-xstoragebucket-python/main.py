@@ -20,7 +20,7 @@ xstorage_bucket = platformv1alpha1.XStorageBucket(
     ),
     spec=platformv1alpha1.Spec(
         parameters=platformv1alpha1.Parameters(
-            acl="public",
+            acl="blob",
             location="eastus",
             versioning=True,
         )
@@ -40,8 +40,10 @@ func main() {
 				ForProvider: &storagev1beta1.AccountSpecForProvider{
 					AccountTier:                     ptr.To("Standard"),
 					AccountReplicationType:          ptr.To("LRS"),
+					AccountKind:                     ptr.To("StorageV2"),
 					Location:                        ptr.To("eastus"),
 					InfrastructureEncryptionEnabled: ptr.To(true),
+					AllowNestedItemsToBePublic:      ptr.To(true), // The blob ACL allows public access.
 					BlobProperties: &[]storagev1beta1.AccountSpecForProviderBlobPropertiesItem{{
 						VersioningEnabled: ptr.To(true),
 					}},
@@ -49,6 +51,10 @@ func main() {
 						MatchControllerRef: ptr.To(true),
 					},
 				},
+				WriteConnectionSecretToRef: &storagev1beta1.AccountSpecWriteConnectionSecretToRef{
+					Name:      ptr.To("example-account"),
+					Namespace: ptr.To("crossplane-system"),
+				},
 			},
 		},
 		// Assert Storage Container with correct ACL mapping.
@@ -57,7 +63,7 @@ func main() {
 			Kind:       ptr.To(storagev1beta1.ContainerKindContainer),
 			Spec: &storagev1beta1.ContainerSpec{
 				ForProvider: &storagev1beta1.ContainerSpecForProvider{
-					ContainerAccessType: ptr.To("blob"), // ACL "public" maps to "blob".
+					ContainerAccessType: ptr.To("blob"), // The example requests the blob ACL.
 					StorageAccountNameSelector: &storagev1beta1.ContainerSpecForProviderStorageAccountNameSelector{
 						MatchControllerRef: ptr.To(true),
 					},
@@ -65,6 +71,30 @@ func main() {
 			},
 		},
 	)
+	// The function composes resources in stages, waiting for each resource's
+	// dependencies to become ready. Observe a ready resource group and storage
+	// account so that every stage is composed.
+	observedResources := resourcesToItems[metav1alpha1.CompositionTestSpecObservedResourcesItem](
+		ready(&azv1beta1.ResourceGroup{
+			APIVersion: ptr.To(azv1beta1.ResourceGroupAPIVersionazureUpboundIoV1Beta1),
+			Kind:       ptr.To(azv1beta1.ResourceGroupKindResourceGroup),
+			Metadata: &metav1.ObjectMeta{
+				Annotations: &map[string]string{
+					"crossplane.io/composition-resource-name": "rg",
+				},
+			},
+		}),
+		ready(&storagev1beta1.Account{
+			APIVersion: ptr.To(storagev1beta1.AccountAPIVersionstorageAzureUpboundIoV1Beta1),
+			Kind:       ptr.To(storagev1beta1.AccountKindAccount),
+			Metadata: &metav1.ObjectMeta{
+				Name: ptr.To("example"),
+				Annotations: &map[string]string{
+					"crossplane.io/composition-resource-name": "account",
+				},
+			},
+		}),
+	)
 	test := metav1alpha1.CompositionTest{
 		APIVersion: ptr.To(metav1alpha1.CompositionTestAPIVersionmetaDevUpboundIoV1Alpha1),
 		Kind:       ptr.To(metav1alpha1.CompositionTestKindCompositionTest),
@@ -72,12 +102,13 @@ func main() {
 			Name: ptr.To(""),
 		},
 		Spec: &metav1alpha1.CompositionTestSpec{
-			AssertResources: &assertResources,
-			CompositionPath: ptr.To("apis/xstoragebuckets/composition.yaml"),
-			XrPath:          ptr.To("examples/xstoragebuckets/example.yaml"),
-			XrdPath:         ptr.To("apis/xstoragebuckets/definition.yaml"),
-			TimeoutSeconds:  ptr.To(120),
-			Validate:        ptr.To(false),
+			AssertResources:   &assertResources,
+			ObservedResources: &observedResources,
+			CompositionPath:   ptr.To("apis/xstoragebuckets/composition.yaml"),
+			XrPath:            ptr.To("examples/xstoragebuckets/example.yaml"),
+			XrdPath:           ptr.To("apis/xstoragebuckets/definition.yaml"),
+			TimeoutSeconds:    ptr.To(120),
+			Validate:          ptr.To(false),
 		},
 	}
 	// Wrap in items array as expected by the test runner.
@@ -92,6 +123,25 @@ func main() {
 	fmt.Print(string(out))
 }
 
+// ready returns the supplied resource with a true Ready condition.
+func ready(resource interface{}) map[string]interface{} {
+	obj := map[string]interface{}{}
+	if err := convertViaJSON(&obj, resource); err != nil {
+		panic(fmt.Sprintf("converting resource: %v", err))
+	}
+	obj["status"] = map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":               "Ready",
+				"status":             "True",
+				"reason":             "Available",
+				"lastTransitionTime": "2024-01-01T00:00:00Z",
+			},
+		},
+	}
+	return obj
+}
+
 func toItem[T any](resource interface{}) T {
 	var item T
 	if err := convertViaJSON(&item, resource); err != nil {
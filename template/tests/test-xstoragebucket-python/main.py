@@ -13,7 +13,7 @@ xStorageBucket = platformv1alpha1.XStorageBucket(
     ),
     spec = platformv1alpha1.Spec(
         parameters = platformv1alpha1.Parameters(
-            acl="public",
+            acl="blob",
             location="eastus",
             versioning=True,
         ),
@@ -50,6 +50,7 @@ account = acctv1beta1.Account(
             accountReplicationType="LRS",
             location="eastus",
             infrastructureEncryptionEnabled=True,
+            allowNestedItemsToBePublic=True,
             blobProperties=[
                 acctv1beta1.BlobProperty(
                     versioningEnabled=True,